@@ -19,7 +19,7 @@ function teardown() {
   assert_success
   assert_output -p "This file was generated"
   assert_output -p "inputs.\"ntv\".url = \"path:$PROJECT_ROOT/nix/flakeModules\";"
-  assert_output -p "inputs.\"hello\".url = \"nixpkgs/"
+  assert_output -p "inputs.\"nixpkgs-"
 
   # lock inputs
   echo "$output" >flake.nix
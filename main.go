@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
 	"slices"
+	"syscall"
 
 	"github.com/vic/ntv/packages/app"
 )
@@ -16,7 +19,9 @@ func main() {
 	if len(os.Args) < 2 {
 		app.HelpDict.PrintHelpAndExit(app.Help, args, 1)
 	}
-	err := app.NewAppArgs().ParseAndRun(args)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := app.NewAppArgs().ParseAndRun(ctx, args)
 	if err != nil {
 		if ee, ok := err.(*exec.ExitError); ok {
 			log.Fatal(string(ee.Stderr))
@@ -14,5 +14,6 @@
       flake.flakeModules.default = ./default.nix;
       flake.flakeModules.devenv-shell = ./devenv-shell.nix;
       flake.flakeModules.nixpkgs-shell = ./nixpkgs-shell.nix;
+      flake.flakeModules.oci-image = ./oci-image.nix;
     };
 }
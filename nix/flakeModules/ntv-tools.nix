@@ -3,26 +3,45 @@
   options.ntv.tools = lib.mkOption {
     description = "Tools pinned to specific versions by ntv.";
     type = lib.types.attrsOf (
-      lib.types.submodule {
-        options = {
-          spec = lib.mkOption {
-            type = lib.types.str;
-            description = "The original spec given to ntv.";
+      lib.types.submodule (
+        { config, ... }:
+        {
+          options = {
+            spec = lib.mkOption {
+              type = lib.types.str;
+              description = "The original spec given to ntv.";
+            };
+            name = lib.mkOption {
+              type = lib.types.str;
+              description = "The resolved name of the package.";
+            };
+            version = lib.mkOption {
+              type = lib.types.str;
+              description = "The resolved version of the package.";
+            };
+            installable = lib.mkOption {
+              type = lib.types.str;
+              description = "The nix installable in the form: flake#attrPath.";
+            };
+            backend = lib.mkOption {
+              type = lib.types.str;
+              default = "";
+              description = "The versions backend that resolved the tool.";
+            };
+            backendSpec = lib.mkOption {
+              type = lib.types.str;
+              default = "";
+              description = "The versions backend chain used to resolve the tool, eg: `nixhub,history`. Used by `ntv upgrade`.";
+            };
+            input = lib.mkOption {
+              type = lib.types.str;
+              default = config.name;
+              defaultText = lib.literalExpression "name";
+              description = "The flake input providing the tool. Tools pinned to the same revision share an input.";
+            };
           };
-          name = lib.mkOption {
-            type = lib.types.str;
-            description = "The resolved name of the package.";
-          };
-          version = lib.mkOption {
-            type = lib.types.str;
-            description = "The resolved version of the package.";
-          };
-          installable = lib.mkOption {
-            type = lib.types.str;
-            description = "The nix installable in the form: flake#attrPath.";
-          };
-        };
-      }
+        }
+      )
     );
   };
 
@@ -0,0 +1,40 @@
+# Adds `packages.<system>.image`, an OCI image containing every ntv tool.
+#
+# Built with dockerTools.buildLayeredImage, each store path (tools included)
+# gets its own layer while they fit in maxLayers.
+{ config, lib, ... }:
+{
+  options.ntv.oci = lib.mkOption {
+    description = "OCI image options.";
+    default = { };
+    type = lib.types.submodule {
+      options = {
+        name = lib.mkOption {
+          type = lib.types.str;
+          default = "ntv";
+          description = "The image name.";
+        };
+        tag = lib.mkOption {
+          type = lib.types.str;
+          default = "latest";
+          description = "The image tag.";
+        };
+        maxLayers = lib.mkOption {
+          type = lib.types.int;
+          default = 125;
+          description = "Store paths beyond this many layers share the last one.";
+        };
+      };
+    };
+  };
+
+  config.perSystem =
+    { pkgs, self', ... }:
+    {
+      packages.image = pkgs.dockerTools.buildLayeredImage {
+        inherit (config.ntv.oci) name tag maxLayers;
+        contents = lib.attrValues self'.packages.default.versioned;
+        config.Env = [ "PATH=/bin" ];
+      };
+    };
+}
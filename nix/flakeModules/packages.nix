@@ -5,15 +5,16 @@
     { pkgs, inputs', ... }:
     let
       # Each config.ntv.tools entry is a tool with a specific version.
-      # it has a corresponding input on the flake.
+      # it has a corresponding input on the flake, shared by all tools
+      # pinned to the same revision.
       #
       # We use the tool attribute to access the tool's versioned package.
       # And place it under the same name in the package set.
       getTool =
         _name: tool:
         let
-          inputHasPackages = inputs.${tool.name} ? packages;
-          input = inputs'.${tool.name};
+          inputHasPackages = inputs.${tool.input} ? packages;
+          input = inputs'.${tool.input};
           inputPkgs = if inputHasPackages then input.packages else input.legacyPackages;
           parts = pkgs.lib.splitString "#" tool.installable;
           attrPath = pkgs.lib.last parts;
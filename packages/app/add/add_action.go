@@ -0,0 +1,52 @@
+package add
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/vic/ntv/packages/app/new"
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+	"github.com/vic/ntv/packages/search_spec"
+)
+
+func (a *AddArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
+	if len(a.rest) == 0 && !a.Oci {
+		return fmt.Errorf("expected at least one package-spec to add")
+	}
+
+	f, err := flake.Load(ctx, a.File)
+	if err != nil {
+		return err
+	}
+
+	if a.Oci {
+		f.Flake.AddImport(flake.OciImageModule)
+	}
+
+	specs, err := search_spec.ParseSearchSpecs(a.rest, a.search.VersionsBackend)
+	if err != nil {
+		return err
+	}
+
+	res, err := search.PackageSearchSpecs(specs.WithNixSearch(a.search.NixSearch())).Search(ctx)
+	if err != nil {
+		return err
+	}
+	a.search.WarnTruncated(res)
+
+	if a.search.Optimize {
+		res.MinimizeRevisions()
+	}
+
+	code, err := new.FlakeCode(ctx, f, res, true)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(a.File, []byte(code), 0o644)
+}
@@ -0,0 +1,60 @@
+package add
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/vic/ntv/packages/app/help"
+	"github.com/vic/ntv/packages/app/search_args"
+	"github.com/vic/ntv/packages/backends"
+)
+
+type AddArgs struct {
+	File   string `long:"file" short:"f"`
+	Oci    bool   `long:"oci"`
+	search *search_args.SearchArgs
+	rest   []string
+}
+
+//go:embed HELP
+var HELP string
+
+var Help = help.CmdHelp{
+	HelpTxt: HELP,
+	HelpCtx: func(name string) any {
+		return map[string]interface{}{
+			"Cmd":      name,
+			"Backends": backends.Registered(),
+		}
+	},
+}
+
+func NewAddArgs() *AddArgs {
+	args := AddArgs{
+		File:   "flake.nix",
+		search: search_args.NewSearchArgs(),
+	}
+	return &args
+}
+
+func (a *AddArgs) Parse(args []string) error {
+	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	if err := a.search.AddTo(parser); err != nil {
+		return err
+	}
+	rest, err := parser.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	a.rest = rest
+	return nil
+}
+
+func (a *AddArgs) ParseAndRun(ctx context.Context, args []string) error {
+	err := a.Parse(args)
+	if err != nil {
+		return err
+	}
+	return a.Run(ctx)
+}
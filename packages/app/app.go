@@ -2,15 +2,24 @@ package app
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
+	"github.com/vic/ntv/packages/app/add"
+	"github.com/vic/ntv/packages/app/cache"
 	"github.com/vic/ntv/packages/app/help"
 	"github.com/vic/ntv/packages/app/list"
 	"github.com/vic/ntv/packages/app/new"
+	"github.com/vic/ntv/packages/app/outdated"
+	"github.com/vic/ntv/packages/app/pick"
+	"github.com/vic/ntv/packages/app/remove"
+	"github.com/vic/ntv/packages/app/search"
+	"github.com/vic/ntv/packages/app/serve"
+	"github.com/vic/ntv/packages/app/upgrade"
 )
 
 //go:embed HELP.txt
@@ -33,8 +42,16 @@ var Help = help.CmdHelp{
 }
 
 var HelpDict = help.HelpDict{
-	"init": new.Help,
-	"list": list.Help,
+	"init":     new.Help,
+	"list":     list.Help,
+	"cache":    cache.Help,
+	"add":      add.Help,
+	"remove":   remove.Help,
+	"upgrade":  upgrade.Help,
+	"outdated": outdated.Help,
+	"search":   search.Help,
+	"pick":     pick.Help,
+	"serve":    serve.Help,
 }
 
 type AppArgs struct {
@@ -70,7 +87,7 @@ func VersionAndExit() {
 	os.Exit(0)
 }
 
-func (a *AppArgs) ParseAndRun(args []string) error {
+func (a *AppArgs) ParseAndRun(ctx context.Context, args []string) error {
 	parser := flags.NewParser(a, flags.IgnoreUnknown)
 	extra, err := parser.ParseArgs(args[1:])
 	if err != nil {
@@ -88,14 +105,45 @@ func (a *AppArgs) ParseAndRun(args []string) error {
 	}
 
 	if cmd == "init" {
-		return new.NewInitArgs().ParseAndRun(extra[1:])
+		return new.NewInitArgs().ParseAndRun(ctx, extra[1:])
 	}
 
 	if cmd == "list" || cmd == "ls" {
-		return list.NewListArgs().ParseAndRun(extra[1:])
+		return list.NewListArgs().ParseAndRun(ctx, extra[1:])
 	}
 
-	// // Default action is search.
-	// return NewSearchArgs().ParseAndRun(extra)
-	return nil
+	if cmd == "add" {
+		return add.NewAddArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "remove" || cmd == "rm" {
+		return remove.NewRemoveArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "upgrade" {
+		return upgrade.NewUpgradeArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "outdated" {
+		return outdated.NewOutdatedArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "pick" {
+		return pick.NewPickArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "serve" {
+		return serve.NewServeArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	if cmd == "cache" {
+		return cache.NewCacheArgs().ParseAndRun(extra[1:])
+	}
+
+	if cmd == "search" {
+		return search.NewSearchArgs().ParseAndRun(ctx, extra[1:])
+	}
+
+	// Default action is search.
+	return search.NewSearchArgs().ParseAndRun(ctx, extra)
 }
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+func (a *CacheArgs) Run() error {
+	var cmd string
+	if len(a.rest) > 0 {
+		cmd = a.rest[0]
+	}
+
+	if cmd == "clear" {
+		return a.cache.Clear()
+	}
+
+	if cmd == "stats" || cmd == "" {
+		out, err := a.StatsOut()
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	return fmt.Errorf("unknown cache command `%s`. expected one of: clear, stats", cmd)
+}
+
+func (a *CacheArgs) StatsOut() (string, error) {
+	stats, err := a.cache.Stats()
+	if err != nil {
+		return "", err
+	}
+
+	if a.JSON {
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"dir":      a.cache.Dir,
+			"ttl":      a.cache.TTL.String(),
+			"backends": stats,
+		}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(jsonBytes), nil
+	}
+
+	color.NoColor = !a.Color
+	hd := color.New(color.Faint).SprintfFunc()
+
+	buff := bytes.Buffer{}
+	fmt.Fprintf(&buff, "%s %s\n", hd("Dir"), a.cache.Dir)
+	fmt.Fprintf(&buff, "%s %s\n\n", hd("TTL"), a.cache.TTL)
+
+	tbl := table.New(hd("Backend"), hd("Entries"), hd("Expired"), hd("Size")).WithWriter(&buff)
+	var entries, expired int
+	var size int64
+	for _, s := range stats {
+		tbl.AddRow(s.Backend, s.Entries, s.Expired, humanSize(s.Size))
+		entries += s.Entries
+		expired += s.Expired
+		size += s.Size
+	}
+	tbl.AddRow(hd("Total"), entries, expired, humanSize(size))
+	tbl.Print()
+	return buff.String(), nil
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
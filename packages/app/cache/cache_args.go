@@ -0,0 +1,57 @@
+package cache
+
+import (
+	_ "embed"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/mattn/go-isatty"
+	"github.com/vic/ntv/packages/app/help"
+	lib "github.com/vic/ntv/packages/cache"
+)
+
+type CacheArgs struct {
+	JSON  bool `long:"json" short:"j"`
+	Color bool `long:"color" short:"C"`
+	cache *lib.Cache
+	rest  []string
+}
+
+//go:embed HELP
+var HELP string
+
+var Help = help.CmdHelp{
+	HelpTxt: HELP,
+	HelpCtx: func(name string) any {
+		return map[string]interface{}{
+			"Cmd": name,
+			"Dir": lib.Default.Dir,
+			"TTL": lib.Default.TTL,
+		}
+	},
+}
+
+func NewCacheArgs() *CacheArgs {
+	return &CacheArgs{
+		Color: isatty.IsTerminal(os.Stdout.Fd()),
+		cache: lib.Default,
+	}
+}
+
+func (a *CacheArgs) Parse(args []string) error {
+	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	rest, err := parser.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	a.rest = rest
+	return nil
+}
+
+func (a *CacheArgs) ParseAndRun(args []string) error {
+	err := a.Parse(args)
+	if err != nil {
+		return err
+	}
+	return a.Run()
+}
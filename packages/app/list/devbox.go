@@ -0,0 +1,51 @@
+package list
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+)
+
+type Devbox struct {
+	Packages []string `json:"packages"`
+}
+
+// DevboxOut generates a devbox.json having the selected version of each tool.
+func DevboxOut(res search.PackageSearchResults) (string, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return "", err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return "", err
+	}
+
+	devbox := Devbox{Packages: []string{}}
+	for _, r := range res {
+		devbox.Packages = append(devbox.Packages, DevboxPackage(r))
+	}
+
+	jsonBytes, err := json.MarshalIndent(&devbox, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// DevboxPackage is a nixhub `attr@version` when resolved by nixhub.
+// Otherwise a flake reference pinned to the nixpkgs revision having the version.
+func DevboxPackage(r *search.PackageSearchResult) string {
+	v := r.Selected
+	if _, isNixHub := r.Backend.(*backends.NixHub); isNixHub {
+		return v.Attribute + "@" + v.Version
+	}
+	if v.Flake == "nixpkgs" && v.Revision != "" {
+		return "github:NixOS/nixpkgs/" + v.Revision + "#" + v.Attribute
+	}
+	if v.Flake == "nixpkgs" {
+		// the system registry nixpkgs is not pinned, let nixhub find the version.
+		return v.Attribute + "@" + v.Version
+	}
+	return strings.Join([]string{v.Flake, v.Attribute}, "#")
+}
@@ -0,0 +1,40 @@
+package list
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+const rev = "0123456789abcdef0123456789abcdef01234567"
+
+func result(backend backends.VersionsBackend, v *lib.Version) *search.PackageSearchResult {
+	spec := v.Name
+	return &search.PackageSearchResult{
+		FromSearch:  &search.PackageSearchSpec{Spec: &spec, Query: &spec},
+		Backend:     backend,
+		Versions:    []*lib.Version{v},
+		Constrained: []*lib.Version{v},
+		Selected:    v,
+	}
+}
+
+func TestDevboxPackage(t *testing.T) {
+	hello := &lib.Version{Name: "hello", Attribute: "hello", Version: "2.12", Flake: "nixpkgs", Revision: rev}
+	assert(t, DevboxPackage(result(&backends.NixHub{}, hello)) == "hello@2.12", "nixhub")
+	assert(t, DevboxPackage(result(&backends.History{}, hello)) == "github:NixOS/nixpkgs/"+rev+"#hello", "pinned revision")
+
+	flk := &lib.Version{Name: "ntv", Attribute: "default", Version: "1.0", Flake: "github:vic/ntv"}
+	assert(t, DevboxPackage(result(&backends.Flake{}, flk)) == "github:vic/ntv#default", "flake")
+}
+
+func TestDevboxOut(t *testing.T) {
+	hello := &lib.Version{Name: "hello", Attribute: "hello", Version: "2.12", Flake: "nixpkgs", Revision: rev}
+	out, err := DevboxOut(search.PackageSearchResults{result(&backends.NixHub{}, hello)})
+	assertNoErr(t, err)
+	assert(t, strings.Contains(out, `"packages": [`), out)
+	assert(t, strings.Contains(out, `"hello@2.12"`), out)
+}
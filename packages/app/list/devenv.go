@@ -0,0 +1,91 @@
+package list
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+)
+
+// devenv `pkgs` come from this input unless already pinned by a tool.
+const DevenvNixpkgs = "github:cachix/devenv-nixpkgs/rolling"
+
+// DevenvOut generates a `devenv.yaml` having an input for each pinned revision or flake,
+// and a `devenv.nix` whose packages come from those inputs.
+func DevenvOut(res search.PackageSearchResults) ([]OutFile, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return nil, err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return nil, err
+	}
+
+	y := bytes.Buffer{}
+	n := bytes.Buffer{}
+	w := func(buff *bytes.Buffer, i int, s string, x ...any) {
+		buff.WriteString(strings.Repeat("  ", i) + fmt.Sprintf(s, x...) + "\n")
+	}
+
+	inputs := []string{}
+	imports := []string{}
+	packages := []string{}
+	w(&y, 0, "# This file was generated by https://github.com/vic/ntv.")
+	w(&y, 0, "inputs:")
+	w(&y, 1, "nixpkgs:")
+	w(&y, 2, "url: %s", DevenvNixpkgs)
+	for _, r := range res {
+		v := r.Selected
+		input := flake.InputName(r)
+		switch {
+		case v.Flake == "nixpkgs" && v.Revision == "":
+			packages = append(packages, "pkgs."+nixAttrPath(v.Attribute))
+			continue
+		case v.Flake == "nixpkgs":
+			for _, attr := range outputAttrs(r) {
+				packages = append(packages, input+"."+attr)
+			}
+			if !slices.Contains(imports, input) {
+				imports = append(imports, input)
+			}
+		default:
+			for _, attr := range outputAttrs(r) {
+				packages = append(packages, fmt.Sprintf("inputs.%s.packages.${pkgs.stdenv.system}.%s", nixAttrPath(input), attr))
+			}
+		}
+		if slices.Contains(inputs, input) {
+			continue
+		}
+		inputs = append(inputs, input)
+		url := v.Flake
+		if v.Revision != "" {
+			url = "github:NixOS/nixpkgs/" + v.Revision
+		}
+		w(&y, 1, "%s:", input)
+		w(&y, 2, "url: %s", url)
+	}
+
+	w(&n, 0, "# This file was generated by https://github.com/vic/ntv.")
+	w(&n, 0, "{ pkgs, inputs, ... }:")
+	if len(imports) > 0 {
+		w(&n, 0, "let")
+		for _, input := range imports {
+			w(&n, 1, "%s = import inputs.%s { inherit (pkgs.stdenv) system; };", input, input)
+		}
+		w(&n, 0, "in")
+	}
+	w(&n, 0, "{")
+	w(&n, 1, "packages = [")
+	for _, p := range packages {
+		w(&n, 2, "%s", p)
+	}
+	w(&n, 1, "];")
+	w(&n, 0, "}")
+
+	return []OutFile{
+		{Path: "devenv.yaml", Content: strings.TrimSuffix(y.String(), "\n")},
+		{Path: "devenv.nix", Content: strings.TrimSuffix(n.String(), "\n")},
+	}, nil
+}
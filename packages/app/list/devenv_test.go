@@ -0,0 +1,26 @@
+package list
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func TestDevenvOut(t *testing.T) {
+	res := append(pinnedResults(),
+		result(&backends.Flake{}, &lib.Version{Name: "ntv", Attribute: "default", Version: "1", Flake: "github:vic/ntv"}))
+	files, err := DevenvOut(res)
+	assertNoErr(t, err)
+	assert(t, len(files) == 2 && files[0].Path == "devenv.yaml" && files[1].Path == "devenv.nix", "devenv files")
+	yaml, code := files[0].Content, files[1].Content
+
+	assert(t, strings.Contains(yaml, "  nixpkgs:\n    url: "+DevenvNixpkgs+"\n"), yaml)
+	assert(t, strings.Count(yaml, "github:NixOS/nixpkgs/"+rev) == 1, "one input per revision")
+	assert(t, strings.HasSuffix(yaml, "  ntv:\n    url: github:vic/ntv"), yaml)
+
+	assert(t, strings.Contains(code, "nixpkgs-0123456 = import inputs.nixpkgs-0123456 { inherit (pkgs.stdenv) system; };"), code)
+	assert(t, strings.Contains(code, "    nixpkgs-0123456.hello\n    nixpkgs-0123456.cowsay\n    pkgs.jq\n"), code)
+	assert(t, strings.Contains(code, "inputs.ntv.packages.${pkgs.stdenv.system}.default"), code)
+}
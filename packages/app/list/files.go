@@ -0,0 +1,76 @@
+package list
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OutFile is one of the files of outputs generating many, like `--devenv`.
+type OutFile struct {
+	// Relative to the `--out` directory. eg. `npins/sources.json`
+	Path    string
+	Content string
+	// Content already has the one of an existing file, eg. merged `npins/sources.json` pins.
+	// Such files are replaced even without `--force`.
+	Update bool
+}
+
+// ShowFiles shows each file after a `==> path <==` header, like `tail` does for many files.
+func ShowFiles(files []OutFile) string {
+	buff := bytes.Buffer{}
+	for i, f := range files {
+		if i > 0 {
+			buff.WriteString("\n")
+		}
+		fmt.Fprintf(&buff, "==> %s <==\n%s\n", f.Path, strings.TrimSuffix(f.Content, "\n"))
+	}
+	return strings.TrimSuffix(buff.String(), "\n")
+}
+
+// WriteFiles into dir and returns their paths. Existing files are only replaced when force is given
+// or they are updated, otherwise nothing is written.
+func WriteFiles(dir string, files []OutFile, force bool) (string, error) {
+	if !force {
+		for _, f := range files {
+			if f.Update {
+				continue
+			}
+			path := filepath.Join(dir, f.Path)
+			if _, err := os.Lstat(path); err == nil {
+				return "", fmt.Errorf("%s already exists. use --force to replace it", path)
+			}
+		}
+	}
+	written := []string{}
+	for _, f := range files {
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(f.Content+"\n"), 0o644); err != nil {
+			return "", err
+		}
+		written = append(written, path)
+	}
+	return strings.Join(written, "\n"), nil
+}
+
+// OutDir where files are written, or the current directory.
+// Existing files there are read by outputs updating them, even when files are only shown.
+func (a *ListArgs) outDir() string {
+	if a.OutDir == "" {
+		return "."
+	}
+	return a.OutDir
+}
+
+// FilesOut shows files, or writes them with `--out DIR`.
+func (a *ListArgs) FilesOut(files []OutFile) (string, error) {
+	if a.OutDir == "" {
+		return ShowFiles(files), nil
+	}
+	return WriteFiles(a.OutDir, files, a.Force)
+}
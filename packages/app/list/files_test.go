@@ -0,0 +1,30 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShowFiles(t *testing.T) {
+	out := ShowFiles([]OutFile{{Path: "a.nix", Content: "a\n"}, {Path: "b/c.json", Content: "{}"}})
+	assert(t, out == "==> a.nix <==\na\n\n==> b/c.json <==\n{}", out)
+}
+
+func TestWriteFiles_refuses_to_replace(t *testing.T) {
+	dir := t.TempDir()
+	files := []OutFile{{Path: "npins/sources.json", Content: "{}"}, {Path: "tools.nix", Content: "{ }"}}
+	os.WriteFile(filepath.Join(dir, "tools.nix"), []byte("mine"), 0o644)
+
+	_, err := WriteFiles(dir, files, false)
+	assert(t, err != nil && strings.Contains(err.Error(), "--force"), "existing file refused")
+	_, err = os.Stat(filepath.Join(dir, "npins/sources.json"))
+	assert(t, os.IsNotExist(err), "nothing written when refused")
+
+	out, err := WriteFiles(dir, files, true)
+	assertNoErr(t, err)
+	assert(t, strings.Contains(out, filepath.Join(dir, "npins/sources.json")), out)
+	content, _ := os.ReadFile(filepath.Join(dir, "tools.nix"))
+	assert(t, string(content) == "{ }\n", string(content))
+}
@@ -3,6 +3,7 @@ package list
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -14,12 +15,16 @@ import (
 	"github.com/rodaine/table"
 
 	"github.com/vic/ntv/packages/app/new"
+	"github.com/vic/ntv/packages/backends"
 	"github.com/vic/ntv/packages/flake"
 	"github.com/vic/ntv/packages/search"
 	"github.com/vic/ntv/packages/search_spec"
 )
 
-func (a *ListArgs) Run() error {
+func (a *ListArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
 	for _, file := range a.ReadFiles {
 		var (
 			more []string
@@ -31,15 +36,29 @@ func (a *ListArgs) Run() error {
 		a.rest = append(a.rest, more...)
 	}
 
-	specs, err := search_spec.ParseSearchSpecs(a.rest, a.versionsBackend)
+	backend := a.search.VersionsBackend
+	if a.MergeBackends {
+		backend = mergedBackends(backend)
+	}
+
+	specs, err := search_spec.ParseSearchSpecs(a.rest, backend)
 	if err != nil {
 		return err
 	}
 
-	res, err := search.PackageSearchSpecs(specs).Search()
+	res, err := search.PackageSearchSpecs(specs.WithNixSearch(a.search.NixSearch())).Search(ctx)
 	if err != nil {
 		return err
 	}
+	a.search.WarnTruncated(res)
+
+	if a.MergeBackends {
+		res.PreferCommonRevisions()
+	}
+
+	if a.search.Optimize {
+		res.MinimizeRevisions()
+	}
 
 	var out string
 	if a.OutFmt == OutText {
@@ -65,7 +84,91 @@ func (a *ListArgs) Run() error {
 
 	if a.OutFmt == OutFlake {
 		f := flake.New()
-		out, err = new.FlakeCode(f, res)
+		out, err = new.FlakeCode(ctx, f, res, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutDevbox {
+		out, err = DevboxOut(res)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutToolVersions {
+		out, err = a.Aliases.ToolVersionsOut(res)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutMise {
+		out, err = a.Aliases.MiseOut(res)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutShellNix {
+		out, err = ShellNixOut(ctx, res, tarballHasher(a.NarHash))
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutNpins {
+		files, err := NpinsOut(ctx, res, tarballHasher(a.NarHash), a.outDir())
+		if err != nil {
+			return err
+		}
+		out, err = a.FilesOut(files)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutNiv {
+		files, err := NivOut(ctx, res, tarballHasher(a.NarHash), a.outDir())
+		if err != nil {
+			return err
+		}
+		out, err = a.FilesOut(files)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutDevenv {
+		files, err := DevenvOut(res)
+		if err != nil {
+			return err
+		}
+		out, err = a.FilesOut(files)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutHomeManager {
+		out, err = ModuleOut(ctx, res, tarballHasher(a.NarHash), HomeManagerPackages)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutNixOSModule {
+		out, err = ModuleOut(ctx, res, tarballHasher(a.NarHash), NixOSPackages)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.OutFmt == OutOci {
+		f := flake.New()
+		f.Flake.AddImport(flake.OciImageModule)
+		out, err = new.FlakeCode(ctx, f, res, true)
 		if err != nil {
 			return err
 		}
@@ -75,6 +178,17 @@ func (a *ListArgs) Run() error {
 	return nil
 }
 
+// nixhub, history and lazamar. Keeps the lazamar channel if one was given.
+func mergedBackends(current backends.VersionsBackend) backends.Merged {
+	lazamar := &backends.Lazamar{Channel: backends.DefaultLazamarChannel}
+	for _, b := range backends.Each(current) {
+		if l, ok := b.(*backends.Lazamar); ok {
+			lazamar = l
+		}
+	}
+	return backends.Merged{&backends.NixHub{}, &backends.History{}, lazamar}
+}
+
 func JsonOut(res search.PackageSearchResults) (string, error) {
 	if err := res.EnsureOneSelected(); err != nil {
 		return "", err
@@ -157,7 +271,7 @@ func (a *ListArgs) TextOut(res search.PackageSearchResults) (string, error) {
 			if r.Package != nil {
 				name = r.Package.AttrName
 			}
-			backend := r.FromSearch.VersionsBackend.String()
+			backend := r.Backend.Name()
 			tbl.AddRow(
 				nameColor(name),
 				versionColor(v.Version),
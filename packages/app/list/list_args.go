@@ -1,13 +1,16 @@
 package list
 
 import (
+	"context"
 	_ "embed"
+	"maps"
 	"os"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/mattn/go-isatty"
 	"github.com/vic/ntv/packages/app/help"
-	"github.com/vic/ntv/packages/search_spec"
+	"github.com/vic/ntv/packages/app/search_args"
+	"github.com/vic/ntv/packages/backends"
 )
 
 type OutFmt uint8
@@ -17,6 +20,16 @@ const (
 	OutText
 	OutInstallable
 	OutFlake
+	OutDevbox
+	OutToolVersions
+	OutMise
+	OutShellNix
+	OutNpins
+	OutNiv
+	OutDevenv
+	OutHomeManager
+	OutNixOSModule
+	OutOci
 )
 
 type ShowOpt uint8
@@ -28,23 +41,36 @@ const (
 )
 
 type ListArgs struct {
-	OnJSON           func()       `long:"json" short:"j"`
-	OnText           func()       `long:"text" short:"t"`
-	OnInstallable    func()       `long:"installable" short:"i"`
-	OnFlake          func()       `long:"flake" short:"f"`
-	OnAll            func()       `long:"all" short:"a"`
-	OnOne            func()       `long:"one" short:"1"`
-	OnNixHub         func()       `long:"nixhub"`
-	OnLazamar        func()       `long:"lazamar"`
-	OnNixPackagesCom func()       `long:"history"`
-	OnRead           func(string) `long:"read" short:"r"`
-	ReadFiles        []string
-	OutFmt           OutFmt
-	ShowOpt          ShowOpt
-	OnLazamarChannel func(string) `long:"channel"`
-	Color            bool         `long:"color" short:"C"`
-	versionsBackend  search_spec.VersionsBackend
-	rest             []string
+	OnJSON         func()             `long:"json" short:"j"`
+	OnText         func()             `long:"text" short:"t"`
+	OnInstallable  func()             `long:"installable" short:"i"`
+	OnFlake        func()             `long:"flake" short:"f"`
+	OnDevbox       func()             `long:"devbox"`
+	OnToolVersions func()             `long:"tool-versions"`
+	OnMise         func()             `long:"mise"`
+	OnShellNix     func()             `long:"shell-nix"`
+	OnNpins        func()             `long:"npins"`
+	OnNiv          func()             `long:"niv"`
+	OnDevenv       func()             `long:"devenv"`
+	OnHomeManager  func()             `long:"home-manager"`
+	OnNixOSModule  func()             `long:"nixos-module"`
+	OnOci          func()             `long:"oci"`
+	NarHash        bool               `long:"nar-hash"`
+	OutDir         string             `long:"out" short:"o"`
+	Force          bool               `long:"force"`
+	OnAlias        func(string) error `long:"alias"`
+	OnAliases      func(string) error `long:"aliases"`
+	Aliases        Aliases
+	OnAll          func()       `long:"all" short:"a"`
+	OnOne          func()       `long:"one" short:"1"`
+	OnRead         func(string) `long:"read" short:"r"`
+	ReadFiles      []string
+	OutFmt         OutFmt
+	ShowOpt        ShowOpt
+	Color          bool `long:"color" short:"C"`
+	MergeBackends  bool `long:"merge-backends" short:"m"`
+	search         *search_args.SearchArgs
+	rest           []string
 }
 
 //go:embed HELP
@@ -54,18 +80,20 @@ var Help = help.CmdHelp{
 	HelpTxt: HELP,
 	HelpCtx: func(name string) any {
 		return map[string]interface{}{
-			"Cmd": name,
+			"Cmd":      name,
+			"Backends": backends.Registered(),
 		}
 	},
 }
 
 func NewListArgs() *ListArgs {
 	args := ListArgs{
-		OutFmt:          OutText,
-		ShowOpt:         ShowConstrained,
-		Color:           isatty.IsTerminal(os.Stdout.Fd()),
-		ReadFiles:       []string{},
-		versionsBackend: search_spec.VersionsBackend{NixHub: &search_spec.Unit{}},
+		OutFmt:    OutText,
+		ShowOpt:   ShowConstrained,
+		Color:     isatty.IsTerminal(os.Stdout.Fd()),
+		ReadFiles: []string{},
+		Aliases:   maps.Clone(DefaultAliases),
+		search:    search_args.NewSearchArgs(),
 	}
 	args.OnRead = func(file string) {
 		args.ReadFiles = append(args.ReadFiles, file)
@@ -82,33 +110,56 @@ func NewListArgs() *ListArgs {
 	args.OnFlake = func() {
 		args.OutFmt = OutFlake
 	}
-	args.OnAll = func() {
-		args.ShowOpt = ShowAll
+	args.OnDevbox = func() {
+		args.OutFmt = OutDevbox
 	}
-	args.OnOne = func() {
-		args.ShowOpt = ShowOne
+	args.OnToolVersions = func() {
+		args.OutFmt = OutToolVersions
 	}
-	args.OnNixHub = func() {
-		args.versionsBackend = search_spec.VersionsBackend{NixHub: &search_spec.Unit{}}
+	args.OnMise = func() {
+		args.OutFmt = OutMise
 	}
-	args.OnLazamar = func() {
-		if args.versionsBackend.LazamarChannel != nil {
-			return
-		}
-		args.OnLazamarChannel("nixpkgs-unstable")
+	args.OnShellNix = func() {
+		args.OutFmt = OutShellNix
 	}
-	args.OnLazamarChannel = func(channel string) {
-		args.versionsBackend = search_spec.VersionsBackend{LazamarChannel: (*search_spec.LazamarChannel)(&channel)}
+	args.OnNpins = func() {
+		args.OutFmt = OutNpins
 	}
-	args.OnNixPackagesCom = func() {
-		args.versionsBackend = search_spec.VersionsBackend{NixPackagesCom: &search_spec.Unit{}}
+	args.OnNiv = func() {
+		args.OutFmt = OutNiv
+	}
+	args.OnDevenv = func() {
+		args.OutFmt = OutDevenv
+	}
+	args.OnHomeManager = func() {
+		args.OutFmt = OutHomeManager
+	}
+	args.OnNixOSModule = func() {
+		args.OutFmt = OutNixOSModule
+	}
+	args.OnOci = func() {
+		args.OutFmt = OutOci
+	}
+	args.OnAlias = func(alias string) error {
+		return args.Aliases.Add(alias)
+	}
+	args.OnAliases = func(file string) error {
+		return args.Aliases.ReadAliases(file)
+	}
+	args.OnAll = func() {
+		args.ShowOpt = ShowAll
+	}
+	args.OnOne = func() {
+		args.ShowOpt = ShowOne
 	}
-
 	return &args
 }
 
 func (a *ListArgs) Parse(args []string) error {
 	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	if err := a.search.AddTo(parser); err != nil {
+		return err
+	}
 	rest, err := parser.ParseArgs(args)
 	if err != nil {
 		return err
@@ -117,10 +168,10 @@ func (a *ListArgs) Parse(args []string) error {
 	return nil
 }
 
-func (a *ListArgs) ParseAndRun(args []string) error {
+func (a *ListArgs) ParseAndRun(ctx context.Context, args []string) error {
 	err := a.Parse(args)
 	if err != nil {
 		return err
 	}
-	return a.Run()
+	return a.Run(ctx)
 }
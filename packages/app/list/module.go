@@ -0,0 +1,185 @@
+package list
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+)
+
+const (
+	HomeManagerPackages = "home.packages"
+	NixOSPackages       = "environment.systemPackages"
+)
+
+// ModuleOut generates a home-manager or NixOS module adding the selected packages to packagesOption.
+//
+// Its `ntv.tools` options follow `nix/flakeModules/ntv-tools.nix`. Each pinned nixpkgs is
+// fetched with `builtins.fetchTarball` unless given as `ntv.inputs.<name>`, eg. a flake input.
+// `system:` tools come from the module `pkgs`.
+func ModuleOut(ctx context.Context, res search.PackageSearchResults, hasher TarballHasher, packagesOption string) (string, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return "", err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return "", err
+	}
+	for _, r := range res {
+		if r.Selected.Flake != "nixpkgs" {
+			return "", fmt.Errorf("`%s` comes from flake `%s`, only nixpkgs packages can be used on modules", *r.FromSearch.Spec, r.Selected.Flake)
+		}
+	}
+	pins, err := nixpkgsPins(ctx, res, hasher)
+	if err != nil {
+		return "", err
+	}
+
+	buff := bytes.Buffer{}
+	buff.WriteString(moduleHeader)
+	w := func(i int, s string, x ...any) {
+		buff.WriteString(strings.Repeat("  ", i) + fmt.Sprintf(s, x...) + "\n")
+	}
+	w(1, "config = {")
+	for _, p := range pins {
+		w(2, "ntv.inputs.%s = lib.mkDefault (", nixString(p.Name))
+		w(3, "builtins.fetchTarball {")
+		w(4, "url = %s;", nixString(p.Url))
+		w(4, "sha256 = %s;", nixString(p.Sha256))
+		w(3, "}")
+		w(2, ");")
+	}
+	for _, r := range res {
+		tool := flake.AsTool(r)
+		w(2, "ntv.tools.%s = {", nixString(tool.Name))
+		w(3, "spec = %s;", nixString(tool.Spec))
+		w(3, "name = %s;", nixString(tool.Name))
+		w(3, "version = %s;", nixString(tool.Version))
+		w(3, "installable = %s;", nixString(tool.Installable))
+		w(3, "backend = %s;", nixString(tool.Backend))
+		w(3, "input = %s;", nixString(tool.Input))
+		w(3, "attr = %s;", nixString(r.Selected.Attribute))
+		if outputs := r.FromSearch.OutputSelectors; len(outputs) > 0 {
+			quoted := []string{}
+			for _, out := range outputs {
+				quoted = append(quoted, nixString(out))
+			}
+			w(3, "outputs = [ %s ];", strings.Join(quoted, " "))
+		}
+		w(2, "};")
+	}
+	w(2, "%s = lib.concatMap (tool: tool.packages) (lib.attrValues cfg.tools);", packagesOption)
+	w(1, "};")
+	w(0, "}")
+	return strings.TrimSuffix(buff.String(), "\n"), nil
+}
+
+// escapes a nix double quoted string.
+func nixString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "${", `\${`)
+	return `"` + s + `"`
+}
+
+const moduleHeader = `# This file was generated by https://github.com/vic/ntv.
+# Pinned nixpkgs are fetched unless given as ` + "`ntv.inputs.<name>`" + `, eg. flake inputs.
+{
+  config,
+  lib,
+  pkgs,
+  ...
+}:
+let
+  cfg = config.ntv;
+  pkgsFor =
+    input:
+    if input == "nixpkgs" then
+      pkgs
+    else
+      import cfg.inputs.${input} {
+        inherit (pkgs.stdenv.hostPlatform) system;
+        inherit (pkgs) config;
+      };
+in
+{
+  options.ntv = {
+    inputs = lib.mkOption {
+      description = "Pinned nixpkgs sources by input name.";
+      type = lib.types.attrsOf lib.types.raw;
+      default = { };
+    };
+    tools = lib.mkOption {
+      description = "Tools pinned to specific versions by ntv.";
+      default = { };
+      type = lib.types.attrsOf (
+        lib.types.submodule (
+          { config, ... }:
+          {
+            options = {
+              spec = lib.mkOption {
+                type = lib.types.str;
+                description = "The original spec given to ntv.";
+              };
+              name = lib.mkOption {
+                type = lib.types.str;
+                description = "The resolved name of the package.";
+              };
+              version = lib.mkOption {
+                type = lib.types.str;
+                description = "The resolved version of the package.";
+              };
+              installable = lib.mkOption {
+                type = lib.types.str;
+                description = "The nix installable in the form: flake#attrPath.";
+              };
+              backend = lib.mkOption {
+                type = lib.types.str;
+                default = "";
+                description = "The versions backend that resolved the tool.";
+              };
+              input = lib.mkOption {
+                type = lib.types.str;
+                default = config.name;
+                defaultText = lib.literalExpression "name";
+                description = "The pinned nixpkgs providing the tool. ` + "`nixpkgs`" + ` is the module pkgs.";
+              };
+              attr = lib.mkOption {
+                type = lib.types.str;
+                default = config.name;
+                defaultText = lib.literalExpression "name";
+                description = "The attribute path of the package on its input.";
+              };
+              package = lib.mkOption {
+                type = lib.types.package;
+                readOnly = true;
+                default = lib.getAttrFromPath (lib.splitString "." config.attr) (pkgsFor config.input);
+                defaultText = lib.literalMD "the ` + "`attr`" + ` package of ` + "`input`" + `";
+                description = "The pinned package.";
+              };
+              outputs = lib.mkOption {
+                type = lib.types.listOf lib.types.str;
+                default = [ ];
+                description = "The selected outputs of the package, like ` + "`pkg^out,dev`" + `. Empty for its default output.";
+              };
+              packages = lib.mkOption {
+                type = lib.types.listOf lib.types.package;
+                readOnly = true;
+                default =
+                  if config.outputs == [ ] then
+                    [ config.package ]
+                  else
+                    map (output: config.package.${output}) config.outputs;
+                defaultText = lib.literalMD "the selected ` + "`outputs`" + ` of ` + "`package`" + `";
+                description = "The packages added to the system or home.";
+              };
+            };
+          }
+        )
+      );
+    };
+  };
+
+`
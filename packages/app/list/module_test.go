@@ -0,0 +1,47 @@
+package list
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func TestModuleOut(t *testing.T) {
+	out, err := ModuleOut(context.Background(), pinnedResults(), fakeHasher, HomeManagerPackages)
+	assertNoErr(t, err)
+	assert(t, strings.Count(out, "builtins.fetchTarball {") == 1, "one fetch per revision")
+	assert(t, strings.Contains(out, `ntv.inputs."nixpkgs-0123456" = lib.mkDefault (`), out)
+	assert(t, strings.Contains(out, `ntv.tools."hello" = {`), out)
+	assert(t, strings.Contains(out, `input = "nixpkgs-0123456";`), out)
+	assert(t, strings.Contains(out, `input = "nixpkgs";`), "system tools use pkgs")
+	assert(t, strings.Contains(out, "home.packages = lib.concatMap (tool: tool.packages)"), out)
+
+	out, err = ModuleOut(context.Background(), pinnedResults(), fakeHasher, NixOSPackages)
+	assertNoErr(t, err)
+	assert(t, strings.Contains(out, "environment.systemPackages = lib.concatMap (tool: tool.packages)"), out)
+}
+
+func TestModuleOut_output_selectors(t *testing.T) {
+	openssl := result(&backends.NixHub{}, &lib.Version{Name: "openssl", Attribute: "openssl", Version: "3", Flake: "nixpkgs", Revision: rev})
+	openssl.FromSearch.OutputSelectors = []string{"out", "dev"}
+	out, err := ModuleOut(context.Background(), search.PackageSearchResults{openssl}, fakeHasher, HomeManagerPackages)
+	assertNoErr(t, err)
+	assert(t, strings.Contains(out, `outputs = [ "out" "dev" ];`), out)
+	assert(t, strings.Count(out, "outputs = [") == 1, "only selected tools have outputs")
+}
+
+func TestModuleOut_rejects_flakes(t *testing.T) {
+	res := search.PackageSearchResults{
+		result(&backends.Flake{}, &lib.Version{Name: "ntv", Attribute: "default", Version: "1", Flake: "github:vic/ntv"}),
+	}
+	_, err := ModuleOut(context.Background(), res, fakeHasher, NixOSPackages)
+	assert(t, err != nil, "only nixpkgs")
+}
+
+func TestNixString(t *testing.T) {
+	assert(t, nixString(`a "b" ${c}`) == `"a \"b\" \${c}"`, nixString(`a "b" ${c}`))
+}
@@ -0,0 +1,129 @@
+package list
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vic/ntv/packages/cache"
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/nix"
+	"github.com/vic/ntv/packages/search"
+)
+
+// TarballHasher returns the `builtins.fetchTarball` sha256 of url.
+type TarballHasher func(ctx context.Context, url string) (string, error)
+
+// a revision tarball never changes, so its hash is cached.
+// Each hasher has its own cache, eg. `nix-prefetch-url` or `nar-hash`,
+// a wrong hash from one is never used by the other.
+func cachedHasher(name string, hasher TarballHasher) TarballHasher {
+	return func(ctx context.Context, url string) (string, error) {
+		return cache.Fetch(cache.Default, cache.Key("tarball", name, url), func() (string, error) {
+			return hasher(ctx, url)
+		})
+	}
+}
+
+func NixpkgsTarballUrl(rev string) string {
+	return fmt.Sprintf("https://github.com/NixOS/nixpkgs/archive/%s.tar.gz", rev)
+}
+
+// ShellNixOut generates a `shell.nix` not needing flakes.
+// Each nixpkgs revision is imported from a pinned tarball, `system:` tools use `<nixpkgs>`.
+func ShellNixOut(ctx context.Context, res search.PackageSearchResults, hasher TarballHasher) (string, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return "", err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return "", err
+	}
+
+	buff := bytes.Buffer{}
+	w := func(i int, s string, x ...any) {
+		buff.WriteString(strings.Repeat("  ", i) + fmt.Sprintf(s, x...) + "\n")
+	}
+
+	imported := []string{}
+	packages := []string{}
+	w(0, "# This file was generated by https://github.com/vic/ntv.")
+	w(0, "let")
+	for _, r := range res {
+		v := r.Selected
+		if v.Flake != "nixpkgs" {
+			return "", fmt.Errorf("`%s` comes from flake `%s` and cannot be used without flakes", *r.FromSearch.Spec, v.Flake)
+		}
+		input := flake.InputName(r)
+		for _, attr := range outputAttrs(r) {
+			packages = append(packages, input+"."+attr)
+		}
+		if slices.Contains(imported, input) {
+			continue
+		}
+		imported = append(imported, input)
+		if v.Revision == "" {
+			w(1, "%s = import <nixpkgs> { };", input)
+			continue
+		}
+		url := NixpkgsTarballUrl(v.Revision)
+		sha256, err := hasher(ctx, url)
+		if err != nil {
+			return "", fmt.Errorf("hashing %s: %w", url, err)
+		}
+		w(1, "%s = import (builtins.fetchTarball {", input)
+		w(2, "url = \"%s\";", url)
+		w(2, "sha256 = \"%s\";", sha256)
+		w(1, "}) { };")
+	}
+	if len(imported) == 0 {
+		w(1, "pkgs = import <nixpkgs> { };")
+	} else {
+		w(1, "pkgs = %s;", imported[0])
+	}
+	w(0, "in")
+	w(0, "pkgs.mkShell {")
+	w(1, "packages = [")
+	for _, p := range packages {
+		w(2, "%s", p)
+	}
+	w(1, "];")
+	w(0, "}")
+	return strings.TrimSuffix(buff.String(), "\n"), nil
+}
+
+// the attribute path of each selected output: `openssl.dev`, or just the attribute.
+func outputAttrs(r *search.PackageSearchResult) []string {
+	attr := nixAttrPath(r.Selected.Attribute)
+	if len(r.FromSearch.OutputSelectors) == 0 {
+		return []string{attr}
+	}
+	attrs := []string{}
+	for _, out := range r.FromSearch.OutputSelectors {
+		attrs = append(attrs, attr+"."+nixAttrPath(out))
+	}
+	return attrs
+}
+
+var nixIdentRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_'-]*$`)
+
+// quotes the parts of attr that are not nix identifiers.
+func nixAttrPath(attr string) string {
+	parts := strings.Split(attr, ".")
+	for i, p := range parts {
+		if !nixIdentRegex.MatchString(p) {
+			parts[i] = fmt.Sprintf("%q", p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// nix-prefetch-url, or computed in go when narHash.
+func tarballHasher(narHash bool) TarballHasher {
+	if narHash {
+		return cachedHasher("nar-hash", nix.FetchTarballHash)
+	}
+	return cachedHasher("nix-prefetch-url", nix.PrefetchTarball)
+}
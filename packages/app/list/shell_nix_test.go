@@ -0,0 +1,69 @@
+package list
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/cache"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func fakeHasher(_ context.Context, url string) (string, error) {
+	return "sha256-of-" + url[len(url)-14:], nil
+}
+
+func TestShellNixOut(t *testing.T) {
+	openssl := result(&backends.NixHub{}, &lib.Version{Name: "openssl", Attribute: "openssl", Version: "3", Flake: "nixpkgs", Revision: rev})
+	openssl.FromSearch.OutputSelectors = []string{"out", "dev"}
+	res := search.PackageSearchResults{
+		result(&backends.NixHub{}, &lib.Version{Name: "hello", Attribute: "hello", Version: "2.12", Flake: "nixpkgs", Revision: rev}),
+		openssl,
+		result(&backends.System{}, &lib.Version{Name: "jq", Attribute: "jq", Version: "1.7", Flake: "nixpkgs"}),
+	}
+	out, err := ShellNixOut(context.Background(), res, fakeHasher)
+	assertNoErr(t, err)
+	assert(t, strings.Count(out, "builtins.fetchTarball") == 1, "one tarball per revision")
+	assert(t, strings.Contains(out, `url = "https://github.com/NixOS/nixpkgs/archive/`+rev+`.tar.gz";`), out)
+	assert(t, strings.Contains(out, `sha256 = "sha256-of-`), out)
+	assert(t, strings.Contains(out, "nixpkgs = import <nixpkgs> { };"), out)
+	assert(t, strings.Contains(out, "pkgs = nixpkgs-0123456;"), out)
+	assert(t, strings.Contains(out, "nixpkgs-0123456.openssl.dev\n"), out)
+	assert(t, strings.Contains(out, "nixpkgs.jq\n"), out)
+}
+
+func TestShellNixOut_rejects_flakes(t *testing.T) {
+	res := search.PackageSearchResults{
+		result(&backends.Flake{}, &lib.Version{Name: "ntv", Attribute: "default", Version: "1", Flake: "github:vic/ntv"}),
+	}
+	_, err := ShellNixOut(context.Background(), res, fakeHasher)
+	assert(t, err != nil, "flakes need flakes")
+}
+
+func TestNixAttrPath(t *testing.T) {
+	assert(t, nixAttrPath("python3Packages.requests") == "python3Packages.requests", "identifiers")
+	assert(t, nixAttrPath("nodePackages.@angular/cli") == `nodePackages."@angular/cli"`, "quoted")
+}
+
+func TestCachedHasher_per_hasher(t *testing.T) {
+	defer func(c cache.Cache) { *cache.Default = c }(*cache.Default)
+	cache.Default.Dir = t.TempDir()
+
+	url := NixpkgsTarballUrl("0123456789abcdef0123456789abcdef01234567")
+	wrong := cachedHasher("a", func(ctx context.Context, url string) (string, error) { return "wrong", nil })
+	right := cachedHasher("b", func(ctx context.Context, url string) (string, error) { return "right", nil })
+
+	hash, _ := wrong(context.Background(), url)
+	assert(t, hash == "wrong", "first hasher")
+	hash, _ = right(context.Background(), url)
+	assert(t, hash == "right", "second hasher does not use the first one cache")
+}
+
+func TestShellNixOut_without_tools(t *testing.T) {
+	out, err := ShellNixOut(context.Background(), search.PackageSearchResults{}, fakeHasher)
+	assertNoErr(t, err)
+	assert(t, strings.Contains(out, "pkgs = import <nixpkgs> { };"), out)
+	assert(t, strings.Contains(out, "packages = [\n  ];"), out)
+}
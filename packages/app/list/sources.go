@@ -0,0 +1,236 @@
+package list
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+)
+
+// NivSource is an entry of niv `nix/sources.json`.
+// Without a branch, `niv update` keeps the pinned revision.
+type NivSource struct {
+	Branch      string `json:"branch,omitempty"`
+	Description string `json:"description"`
+	Homepage    string `json:"homepage"`
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	Rev         string `json:"rev"`
+	Sha256      string `json:"sha256"`
+	Type        string `json:"type"`
+	Url         string `json:"url"`
+	UrlTemplate string `json:"url_template"`
+}
+
+// NpinsPin is a frozen git pin of npins `npins/sources.json`.
+type NpinsPin struct {
+	Type       string          `json:"type"`
+	Repository NpinsRepository `json:"repository"`
+	Branch     string          `json:"branch"`
+	Submodules bool            `json:"submodules"`
+	Revision   string          `json:"revision"`
+	Url        string          `json:"url"`
+	Hash       string          `json:"hash"`
+	Frozen     bool            `json:"frozen"`
+}
+
+type NpinsRepository struct {
+	Type  string `json:"type"`
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+}
+
+type NpinsSources struct {
+	Pins    map[string]NpinsPin `json:"pins"`
+	Version int                 `json:"version"`
+}
+
+// a pinned nixpkgs revision, named like its flake input: `nixpkgs-<shortrev>`
+type nixpkgsPin struct {
+	Name, Rev, Url, Sha256 string
+}
+
+// every unique nixpkgs revision on res.
+// Tools without revision (eg. `system:`) are not pinned, tools from other flakes cannot be.
+func nixpkgsPins(ctx context.Context, res search.PackageSearchResults, hasher TarballHasher) ([]nixpkgsPin, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return nil, err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return nil, err
+	}
+	pins := []nixpkgsPin{}
+	seen := map[string]bool{}
+	for _, r := range res {
+		v := r.Selected
+		if v.Flake != "nixpkgs" {
+			return nil, fmt.Errorf("`%s` comes from flake `%s` and cannot be used without flakes", *r.FromSearch.Spec, v.Flake)
+		}
+		if v.Revision == "" || seen[v.Revision] {
+			continue
+		}
+		seen[v.Revision] = true
+		url := NixpkgsTarballUrl(v.Revision)
+		sha256, err := hasher(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", url, err)
+		}
+		pins = append(pins, nixpkgsPin{Name: flake.InputName(r), Rev: v.Revision, Url: url, Sha256: sha256})
+	}
+	return pins, nil
+}
+
+// NivOut generates a niv `nix/sources.json` and a `tools.nix` using it.
+// Other sources of an existing `nix/sources.json` in dir are kept.
+func NivOut(ctx context.Context, res search.PackageSearchResults, hasher TarballHasher, dir string) ([]OutFile, error) {
+	pins, err := nixpkgsPins(ctx, res, hasher)
+	if err != nil {
+		return nil, err
+	}
+	const path = "nix/sources.json"
+	sources := map[string]json.RawMessage{}
+	if err := readSources(dir, path, &sources); err != nil {
+		return nil, err
+	}
+	for _, p := range pins {
+		sources[p.Name], err = json.Marshal(NivSource{
+			Description: "Nix Packages collection",
+			Homepage:    "",
+			Owner:       "NixOS",
+			Repo:        "nixpkgs",
+			Rev:         p.Rev,
+			Sha256:      p.Sha256,
+			Type:        "tarball",
+			Url:         p.Url,
+			UrlTemplate: "https://github.com/<owner>/<repo>/archive/<rev>.tar.gz",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	jsonBytes, err := json.MarshalIndent(sources, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return []OutFile{
+		{Path: path, Content: string(jsonBytes), Update: true},
+		{Path: "tools.nix", Content: toolsNix(res, "./nix/sources.nix", "`niv init`")},
+	}, nil
+}
+
+// NpinsOut generates an `npins/sources.json` and a `tools.nix` using it.
+// Pins are frozen so `npins update` keeps them.
+// Other pins of an existing `npins/sources.json` in dir are kept.
+func NpinsOut(ctx context.Context, res search.PackageSearchResults, hasher TarballHasher, dir string) ([]OutFile, error) {
+	pins, err := nixpkgsPins(ctx, res, hasher)
+	if err != nil {
+		return nil, err
+	}
+	const path = "npins/sources.json"
+	sources := struct {
+		Pins    map[string]json.RawMessage `json:"pins"`
+		Version int                        `json:"version"`
+	}{Version: 5}
+	if err := readSources(dir, path, &sources); err != nil {
+		return nil, err
+	}
+	if sources.Pins == nil {
+		sources.Pins = map[string]json.RawMessage{}
+	}
+	for _, p := range pins {
+		sources.Pins[p.Name], err = json.Marshal(NpinsPin{
+			Type:       "Git",
+			Repository: NpinsRepository{Type: "GitHub", Owner: "NixOS", Repo: "nixpkgs"},
+			Branch:     "nixpkgs-unstable",
+			Submodules: false,
+			Revision:   p.Rev,
+			Url:        p.Url,
+			Hash:       p.Sha256,
+			Frozen:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	jsonBytes, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []OutFile{
+		{Path: path, Content: string(jsonBytes), Update: true},
+		{Path: "tools.nix", Content: toolsNix(res, "./npins", "`npins init`")},
+	}, nil
+}
+
+// readSources of an existing sources file into sources. Missing files are not an error.
+// Entries not known to ntv are read as raw json so they are written back unchanged.
+func readSources(dir string, path string, sources any) error {
+	content, err := os.ReadFile(filepath.Join(dir, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, sources); err != nil {
+		return fmt.Errorf("reading %s: %w", filepath.Join(dir, path), err)
+	}
+	return nil
+}
+
+// toolsNix is an attribute set of the package of each tool, taken from its pin on sources.
+// Tools without revision (eg. `system:`) use `<nixpkgs>`.
+// The sources loader is not generated, it is created by the init command.
+func toolsNix(res search.PackageSearchResults, sources string, initCmd string) string {
+	buff := bytes.Buffer{}
+	w := func(i int, s string, x ...any) {
+		buff.WriteString(strings.Repeat("  ", i) + fmt.Sprintf(s, x...) + "\n")
+	}
+	w(0, "# This file was generated by https://github.com/vic/ntv.")
+	w(0, "# Each tool comes from its nixpkgs pin on %s, created by %s.", sources, initCmd)
+	w(0, "let")
+	w(1, "sources = import %s;", sources)
+	imported := []string{}
+	for _, r := range res {
+		input := flake.InputName(r)
+		if slices.Contains(imported, input) {
+			continue
+		}
+		imported = append(imported, input)
+		if r.Selected.Revision == "" {
+			w(1, "%s = import <nixpkgs> { };", input)
+			continue
+		}
+		w(1, "%s = import sources.%s { };", input, input)
+	}
+	w(0, "in")
+	w(0, "{")
+	for _, r := range res {
+		input := flake.InputName(r)
+		attrs := outputAttrs(r)
+		name := r.Selected.Name
+		if !nixIdentRegex.MatchString(name) {
+			name = fmt.Sprintf("%q", name)
+		}
+		if len(attrs) == 1 {
+			w(1, "%s = %s.%s;", name, input, attrs[0])
+			continue
+		}
+		w(1, "%s = [", name)
+		for _, attr := range attrs {
+			w(2, "%s.%s", input, attr)
+		}
+		w(1, "];")
+	}
+	w(0, "}")
+	return strings.TrimSuffix(buff.String(), "\n")
+}
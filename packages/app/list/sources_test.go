@@ -0,0 +1,112 @@
+package list
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func pinnedResults() search.PackageSearchResults {
+	return search.PackageSearchResults{
+		result(&backends.NixHub{}, &lib.Version{Name: "hello", Attribute: "hello", Version: "2.12", Flake: "nixpkgs", Revision: rev}),
+		result(&backends.NixHub{}, &lib.Version{Name: "cowsay", Attribute: "cowsay", Version: "3", Flake: "nixpkgs", Revision: rev}),
+		result(&backends.System{}, &lib.Version{Name: "jq", Attribute: "jq", Version: "1.7", Flake: "nixpkgs"}),
+	}
+}
+
+func TestNpinsOut(t *testing.T) {
+	files, err := NpinsOut(context.Background(), pinnedResults(), fakeHasher, t.TempDir())
+	assertNoErr(t, err)
+	assert(t, len(files) == 2 && files[0].Path == "npins/sources.json", "sources.json and tools.nix")
+	out := files[0].Content
+	sources := NpinsSources{}
+	assertNoErr(t, json.Unmarshal([]byte(out), &sources))
+	assert(t, len(sources.Pins) == 1, "one pin per revision")
+	pin := sources.Pins["nixpkgs-0123456"]
+	assert(t, pin.Revision == rev && pin.Frozen, out)
+	assert(t, pin.Hash == "sha256-of-1234567.tar.gz", out)
+}
+
+func TestNivOut(t *testing.T) {
+	files, err := NivOut(context.Background(), pinnedResults(), fakeHasher, t.TempDir())
+	assertNoErr(t, err)
+	assert(t, len(files) == 2 && files[0].Path == "nix/sources.json", "sources.json and tools.nix")
+	out := files[0].Content
+	sources := map[string]NivSource{}
+	assertNoErr(t, json.Unmarshal([]byte(out), &sources))
+	assert(t, len(sources) == 1, "one source per revision")
+	assert(t, sources["nixpkgs-0123456"].Rev == rev, out)
+	assert(t, sources["nixpkgs-0123456"].Type == "tarball", out)
+	assert(t, sources["nixpkgs-0123456"].Branch == "", "without branch niv update keeps the revision")
+}
+
+func TestNpinsOut_keeps_existing_pins(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "npins"), 0o755)
+	existing := `{"pins": {"home-manager": {"type": "Git", "revision": "abc", "extra": [1, 2]}}, "version": 5}`
+	os.WriteFile(filepath.Join(dir, "npins/sources.json"), []byte(existing), 0o644)
+
+	files, err := NpinsOut(context.Background(), pinnedResults(), fakeHasher, dir)
+	assertNoErr(t, err)
+	assert(t, files[0].Update, "sources.json is updated")
+	sources := struct{ Pins map[string]json.RawMessage }{}
+	assertNoErr(t, json.Unmarshal([]byte(files[0].Content), &sources))
+	assert(t, len(sources.Pins) == 2, files[0].Content)
+	pin := bytes.Buffer{}
+	assertNoErr(t, json.Compact(&pin, sources.Pins["home-manager"]))
+	assert(t, pin.String() == `{"type":"Git","revision":"abc","extra":[1,2]}`, "other pins unchanged: "+pin.String())
+
+	_, err = WriteFiles(dir, files, false)
+	assertNoErr(t, err)
+}
+
+func TestNivOut_keeps_existing_sources(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "nix"), 0o755)
+	os.WriteFile(filepath.Join(dir, "nix/sources.json"), []byte(`{"niv": {"branch": "master", "rev": "abc"}}`), 0o644)
+
+	files, err := NivOut(context.Background(), pinnedResults(), fakeHasher, dir)
+	assertNoErr(t, err)
+	sources := map[string]NivSource{}
+	assertNoErr(t, json.Unmarshal([]byte(files[0].Content), &sources))
+	assert(t, len(sources) == 2 && sources["niv"].Branch == "master", files[0].Content)
+}
+
+func TestNpinsOut_tools_nix(t *testing.T) {
+	files, err := NpinsOut(context.Background(), pinnedResults(), fakeHasher, t.TempDir())
+	assertNoErr(t, err)
+	tools := files[1]
+	assert(t, tools.Path == "tools.nix", tools.Path)
+	for _, expected := range []string{
+		"sources = import ./npins;",
+		"created by `npins init`",
+		"nixpkgs-0123456 = import sources.nixpkgs-0123456 { };",
+		"nixpkgs = import <nixpkgs> { };",
+		"hello = nixpkgs-0123456.hello;",
+		"cowsay = nixpkgs-0123456.cowsay;",
+		"jq = nixpkgs.jq;",
+	} {
+		assert(t, strings.Contains(tools.Content, expected), "expected "+expected+" on:\n"+tools.Content)
+	}
+	assert(t, strings.Count(tools.Content, "import sources.nixpkgs-0123456") == 1, "each pin imported once")
+
+	files, err = NivOut(context.Background(), pinnedResults(), fakeHasher, t.TempDir())
+	assertNoErr(t, err)
+	assert(t, strings.Contains(files[1].Content, "sources = import ./nix/sources.nix;"), files[1].Content)
+}
+
+func TestNpinsOut_other_flakes(t *testing.T) {
+	res := search.PackageSearchResults{
+		result(&backends.Flake{}, &lib.Version{Name: "hello", Attribute: "hello", Version: "1", Flake: "github:owner/repo"}),
+	}
+	_, err := NpinsOut(context.Background(), res, fakeHasher, t.TempDir())
+	assert(t, err != nil, "flakes cannot be pinned")
+}
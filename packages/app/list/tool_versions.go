@@ -0,0 +1,175 @@
+package list
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/vic/ntv/packages/search"
+)
+
+// Aliases maps nixpkgs attribute names to asdf plugin names. mise accepts them too.
+type Aliases map[string]string
+
+// Only names differing from their nixpkgs attribute are needed.
+var DefaultAliases = Aliases{
+	"go":                "golang",
+	"python3":           "python",
+	"jdk":               "java",
+	"openjdk":           "java",
+	"temurin-bin":       "java",
+	"rustc":             "rust",
+	"kubernetes-helm":   "helm",
+	"nodePackages.pnpm": "pnpm",
+}
+
+// trailing versions on attribute names like `nodejs_22`, `ruby_3_3` or `emacs-29`
+var attrVersionRegex = regexp.MustCompile(`[_-][0-9]+(_[0-9]+)*$`)
+
+// attributes having their version appended without separator, like `python312` or `php83`.
+// Other trailing digits are part of the name, eg. `bzip2`, `lz4` or `gnum4`.
+var gluedVersionRegex = regexp.MustCompile(`^(python3|php|jdk|openjdk|gcc|perl|lua)[0-9]+$`)
+
+// ToolName for attr. Versioned attributes use the alias of their unversioned name.
+func (al Aliases) ToolName(attr string) string {
+	if name, ok := al[attr]; ok {
+		return name
+	}
+	unversioned := attrVersionRegex.ReplaceAllString(attr, "")
+	if m := gluedVersionRegex.FindStringSubmatch(unversioned); m != nil {
+		unversioned = m[1]
+	}
+	if unversioned == "" {
+		return attr
+	}
+	if name, ok := al[unversioned]; ok {
+		return name
+	}
+	return unversioned
+}
+
+// Add an alias given as `attr=name`.
+func (al Aliases) Add(alias string) error {
+	attr, name, ok := strings.Cut(alias, "=")
+	if !ok || attr == "" || name == "" {
+		return fmt.Errorf("expected alias as `attr=name`, got `%s`", alias)
+	}
+	al[strings.TrimSpace(attr)] = strings.TrimSpace(name)
+	return nil
+}
+
+// ReadAliases from file, having `attr name` or `attr=name` lines. `#` starts a comment.
+func (al Aliases) ReadAliases(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("expected `attr name` on %s, got `%s`", file, scanner.Text())
+		}
+		al[fields[0]] = fields[1]
+	}
+	return scanner.Err()
+}
+
+// the tool name of the selected version.
+func (al Aliases) toolName(r *search.PackageSearchResult) string {
+	v := r.Selected
+	if v.Flake != "nixpkgs" {
+		return al.ToolName(v.Name)
+	}
+	return al.ToolName(v.Attribute)
+}
+
+// different attributes can have the same tool name, eg. `nodejs_20` and `nodejs_22`.
+func (al Aliases) ensureUniqueToolNames(res search.PackageSearchResults) error {
+	attrs := map[string]string{}
+	for _, r := range res {
+		name := al.toolName(r)
+		if attr, seen := attrs[name]; seen {
+			return fmt.Errorf("both `%s` and `%s` are named `%s`. use --alias to name them differently", attr, r.Selected.Attribute, name)
+		}
+		attrs[name] = r.Selected.Attribute
+	}
+	return nil
+}
+
+// ToolVersionsOut generates an asdf `.tool-versions` file with the exact selected versions.
+func (al Aliases) ToolVersionsOut(res search.PackageSearchResults) (string, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return "", err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return "", err
+	}
+	if err := al.ensureUniqueToolNames(res); err != nil {
+		return "", err
+	}
+	buff := bytes.Buffer{}
+	for _, r := range res {
+		fmt.Fprintf(&buff, "%s %s\n", al.toolName(r), r.Selected.Version)
+	}
+	return strings.TrimSuffix(buff.String(), "\n"), nil
+}
+
+// bare toml keys, others need quoting.
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MiseOut generates a `mise.toml` with the exact selected versions.
+func (al Aliases) MiseOut(res search.PackageSearchResults) (string, error) {
+	if err := res.EnsureOneSelected(); err != nil {
+		return "", err
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return "", err
+	}
+	if err := al.ensureUniqueToolNames(res); err != nil {
+		return "", err
+	}
+	buff := bytes.Buffer{}
+	fmt.Fprintln(&buff, "[tools]")
+	for _, r := range res {
+		name := al.toolName(r)
+		if !tomlBareKeyRegex.MatchString(name) {
+			name = tomlString(name)
+		}
+		fmt.Fprintf(&buff, "%s = %s\n", name, tomlString(r.Selected.Version))
+	}
+	return strings.TrimSuffix(buff.String(), "\n"), nil
+}
+
+// a toml basic string. Unlike go, toml only has `\uXXXX` escapes for control characters.
+func tomlString(s string) string {
+	buff := strings.Builder{}
+	buff.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			buff.WriteRune('\\')
+			buff.WriteRune(c)
+		case c == '\n':
+			buff.WriteString(`\n`)
+		case c == '\t':
+			buff.WriteString(`\t`)
+		case c == '\r':
+			buff.WriteString(`\r`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&buff, `\u%04X`, c)
+		default:
+			buff.WriteRune(c)
+		}
+	}
+	buff.WriteByte('"')
+	return buff.String()
+}
@@ -0,0 +1,72 @@
+package list
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func TestToolName(t *testing.T) {
+	assert(t, DefaultAliases.ToolName("go") == "golang", "renamed")
+	assert(t, DefaultAliases.ToolName("nodejs_22") == "nodejs", "unversioned")
+	assert(t, DefaultAliases.ToolName("python312") == "python", "unversioned alias")
+	assert(t, DefaultAliases.ToolName("ripgrep") == "ripgrep", "same name")
+	assert(t, DefaultAliases.ToolName("ruby_3_3") == "ruby", "multi part version")
+	assert(t, DefaultAliases.ToolName("php83") == "php", "glued version")
+	assert(t, DefaultAliases.ToolName("lua5_4") == "lua", "glued and separated version")
+	for _, attr := range []string{"bzip2", "lz4", "gnum4", "p7zip", "libxml2"} {
+		assert(t, DefaultAliases.ToolName(attr) == attr, "digits are part of "+attr)
+	}
+	assert(t, DefaultAliases.ToolName("python3") == "python", "python3 is an alias")
+}
+
+func TestAliases_Add(t *testing.T) {
+	al := Aliases{}
+	assertNoErr(t, al.Add("nodejs=node"))
+	assert(t, al.ToolName("nodejs_20") == "node", "custom alias")
+	assert(t, al.Add("nodejs") != nil, "invalid alias")
+}
+
+func TestAliases_ReadAliases(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "aliases")
+	assertNoErr(t, os.WriteFile(file, []byte("# aliases\nnodejs node # mise\n\ngo=go\n"), 0o644))
+	al := Aliases{}
+	assertNoErr(t, al.ReadAliases(file))
+	assert(t, al["nodejs"] == "node" && al["go"] == "go", "read aliases")
+}
+
+func TestToolVersionsAndMiseOut(t *testing.T) {
+	res := search.PackageSearchResults{
+		result(&backends.NixHub{}, &lib.Version{Name: "go", Attribute: "go_1_24", Version: "1.24.1", Flake: "nixpkgs", Revision: rev}),
+		result(&backends.NixHub{}, &lib.Version{Name: "nodejs", Attribute: "nodejs_22", Version: "22.1.0", Flake: "nixpkgs", Revision: rev}),
+	}
+	out, err := DefaultAliases.ToolVersionsOut(res)
+	assertNoErr(t, err)
+	assert(t, out == "golang 1.24.1\nnodejs 22.1.0", out)
+
+	out, err = DefaultAliases.MiseOut(res)
+	assertNoErr(t, err)
+	assert(t, out == "[tools]\ngolang = \"1.24.1\"\nnodejs = \"22.1.0\"", out)
+}
+
+func TestMiseOut_rejects_same_tool_names(t *testing.T) {
+	res := search.PackageSearchResults{
+		result(&backends.NixHub{}, &lib.Version{Name: "nodejs", Attribute: "nodejs_20", Version: "20.1.0", Flake: "nixpkgs", Revision: rev}),
+		result(&backends.NixHub{}, &lib.Version{Name: "nodejs-slim", Attribute: "nodejs_22", Version: "22.1.0", Flake: "nixpkgs", Revision: rev}),
+	}
+	_, err := DefaultAliases.MiseOut(res)
+	assert(t, err != nil && strings.Contains(err.Error(), "`nodejs`"), "same tool name")
+	_, err = DefaultAliases.ToolVersionsOut(res)
+	assert(t, err != nil, "same tool name on .tool-versions")
+}
+
+func TestTomlString(t *testing.T) {
+	assert(t, tomlString(`a "b" \c`) == `"a \"b\" \\c"`, tomlString(`a "b" \c`))
+	assert(t, tomlString("\x01\x7f\t") == `"\u0001\u007F\t"`, tomlString("\x01\x7f\t"))
+	assert(t, tomlString("ü😀") == `"ü😀"`, "unicode kept as is")
+}
@@ -1,6 +1,7 @@
 package new
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vic/ntv/packages/flake"
@@ -8,24 +9,32 @@ import (
 	"github.com/vic/ntv/packages/search_spec"
 )
 
-func (a *InitArgs) Run() error {
+func (a *InitArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
 	f := flake.New()
 
 	if a.NtvFlake != "" {
 		f.Flake.OverrideInput("ntv", a.NtvFlake)
 	}
 
-	specs, err := search_spec.ParseSearchSpecs(a.rest, a.versionsBackend)
+	specs, err := search_spec.ParseSearchSpecs(a.rest, a.search.VersionsBackend)
 	if err != nil {
 		return err
 	}
 
-	res, err := search.PackageSearchSpecs(specs).Search()
+	res, err := search.PackageSearchSpecs(specs.WithNixSearch(a.search.NixSearch())).Search(ctx)
 	if err != nil {
 		return err
 	}
+	a.search.WarnTruncated(res)
+
+	if a.search.Optimize {
+		res.MinimizeRevisions()
+	}
 
-	code, err := FlakeCode(f, res)
+	code, err := FlakeCode(ctx, f, res, true)
 	if err != nil {
 		return err
 	}
@@ -34,7 +43,7 @@ func (a *InitArgs) Run() error {
 	return nil
 }
 
-func FlakeCode(f *flake.Context, res search.PackageSearchResults) (string, error) {
+func FlakeCode(ctx context.Context, f *flake.Context, res search.PackageSearchResults, canRunNix bool) (string, error) {
 	if err := res.EnsureOneSelected(); err != nil {
 		return "", err
 	}
@@ -46,5 +55,5 @@ func FlakeCode(f *flake.Context, res search.PackageSearchResults) (string, error
 		f.AddTool(r)
 	}
 
-	return f.Render(true)
+	return f.Render(ctx, canRunNix)
 }
@@ -1,21 +1,19 @@
 package new
 
 import (
+	"context"
 	_ "embed"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/vic/ntv/packages/app/help"
-	"github.com/vic/ntv/packages/search_spec"
+	"github.com/vic/ntv/packages/app/search_args"
+	"github.com/vic/ntv/packages/backends"
 )
 
 type InitArgs struct {
-	OnNixHub         func()       `long:"nixhub" short:"n"`
-	OnLazamar        func()       `long:"lazamar" short:"l"`
-	OnLazamarChannel func(string) `long:"channel" short:"c"`
-	OnNixPackagesCom func()       `long:"history" short:"h"`
-	NtvFlake         string       `long:"override-ntv"`
-	versionsBackend  search_spec.VersionsBackend
-	rest             []string
+	NtvFlake string `long:"override-ntv"`
+	search   *search_args.SearchArgs
+	rest     []string
 }
 
 //go:embed HELP
@@ -25,38 +23,24 @@ var Help = help.CmdHelp{
 	HelpTxt: HELP,
 	HelpCtx: func(name string) any {
 		return map[string]interface{}{
-			"Cmd": name,
+			"Cmd":      name,
+			"Backends": backends.Registered(),
 		}
 	},
 }
 
 func NewInitArgs() *InitArgs {
 	args := InitArgs{
-		versionsBackend: search_spec.VersionsBackend{
-			NixHub: &search_spec.Unit{},
-		},
-	}
-
-	args.OnNixHub = func() {
-		args.versionsBackend = search_spec.VersionsBackend{NixHub: &search_spec.Unit{}}
-	}
-	args.OnLazamar = func() {
-		if args.versionsBackend.LazamarChannel != nil {
-			return
-		}
-		args.OnLazamarChannel("nixpkgs-unstable")
-	}
-	args.OnLazamarChannel = func(channel string) {
-		args.versionsBackend = search_spec.VersionsBackend{LazamarChannel: (*search_spec.LazamarChannel)(&channel)}
-	}
-	args.OnNixPackagesCom = func() {
-		args.versionsBackend = search_spec.VersionsBackend{NixPackagesCom: &search_spec.Unit{}}
+		search: search_args.NewSearchArgs(),
 	}
 	return &args
 }
 
 func (a *InitArgs) Parse(args []string) error {
 	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	if err := a.search.AddTo(parser); err != nil {
+		return err
+	}
 	rest, err := parser.ParseArgs(args)
 	if err != nil {
 		return err
@@ -65,10 +49,10 @@ func (a *InitArgs) Parse(args []string) error {
 	return nil
 }
 
-func (a *InitArgs) ParseAndRun(args []string) error {
+func (a *InitArgs) ParseAndRun(ctx context.Context, args []string) error {
 	err := a.Parse(args)
 	if err != nil {
 		return err
 	}
-	return a.Run()
+	return a.Run(ctx)
 }
@@ -0,0 +1,145 @@
+package outdated
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+type Outdated struct {
+	Name    string `json:"name"`
+	Spec    string `json:"spec"`
+	Backend string `json:"backend"`
+	// The version pinned on the flake.
+	Current string `json:"current"`
+	// The newest version that meets the spec.
+	Wanted string `json:"wanted"`
+	// The newest version known to the backend, even if the spec does not allow it.
+	Latest   string `json:"latest"`
+	Outdated bool   `json:"outdated"`
+	// Why the tool could not be searched again.
+	Error string `json:"error,omitempty"`
+}
+
+func (a *OutdatedArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
+	f, err := flake.Load(ctx, a.File)
+	if err != nil {
+		return err
+	}
+
+	resolved, failed, err := f.ResolveEach(ctx, a.rest, a.search.VersionsBackend, a.search.NixSearch())
+	if err != nil {
+		return err
+	}
+	a.search.WarnTruncated(slices.Collect(maps.Values(resolved)))
+
+	report := Report(f, resolved, failed)
+	out, err := a.ReportOut(report)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+
+	if !a.FailOnOutdated {
+		return nil
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d tools could not be searched", len(failed), len(report))
+	}
+	outdated := slices.DeleteFunc(slices.Clone(report), func(o Outdated) bool { return !o.Outdated })
+	if len(outdated) > 0 {
+		return fmt.Errorf("%d of %d tools are outdated", len(outdated), len(report))
+	}
+	return nil
+}
+
+// Report compares the pinned version of each tool with the resolved ones, sorted by name.
+// Tools that failed to resolve are reported with their error.
+func Report(f *flake.Context, resolved map[string]*search.PackageSearchResult, failed map[string]error) []Outdated {
+	report := []Outdated{}
+	for name, err := range failed {
+		tool := f.Tools[name]
+		report = append(report, Outdated{
+			Name:    name,
+			Spec:    tool.Spec,
+			Backend: tool.Backend,
+			Current: tool.Version,
+			Error:   err.Error(),
+		})
+	}
+	for name, r := range resolved {
+		tool := f.Tools[name]
+		o := Outdated{
+			Name:    name,
+			Spec:    tool.Spec,
+			Backend: r.Backend.Name(),
+			Current: tool.Version,
+		}
+		if r.Selected != nil {
+			o.Wanted = r.Selected.Version
+		}
+		if len(r.Versions) > 0 {
+			o.Latest = r.Versions[len(r.Versions)-1].Version
+		}
+		// a newer Latest the spec does not allow cannot be upgraded to,
+		// nor an older Wanted, eg. found by a fallback backend.
+		o.Outdated = o.Wanted != "" && o.Current != o.Wanted &&
+			lib.ByVersion{{Version: o.Current}, {Version: o.Wanted}}.Less(0, 1)
+		report = append(report, o)
+	}
+	slices.SortFunc(report, func(a, b Outdated) int { return strings.Compare(a.Name, b.Name) })
+	return report
+}
+
+func (a *OutdatedArgs) ReportOut(report []Outdated) (string, error) {
+	if a.JSON {
+		jsonBytes, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(jsonBytes) + "\n", nil
+	}
+
+	color.NoColor = !a.Color
+
+	hd := color.New(color.Faint).SprintfFunc()
+	same := color.New(color.Faint).SprintfFunc()
+	current := color.New(color.FgRed).SprintfFunc()
+	wanted := color.New(color.FgHiGreen).SprintfFunc()
+	latest := color.New(color.FgHiMagenta).SprintfFunc()
+	failed := color.New(color.FgRed, color.Bold).SprintfFunc()
+
+	buff := bytes.Buffer{}
+	tbl := table.New(hd("Name"), hd("Current"), hd("Wanted"), hd("Latest"), hd("Spec")).WithWriter(&buff)
+	for _, o := range report {
+		if o.Error != "" {
+			tbl.AddRow(o.Name, o.Current, failed("error"), failed(o.Error), hd(o.Spec))
+			continue
+		}
+		if !o.Outdated {
+			newer := same
+			if o.Latest != o.Wanted {
+				newer = latest
+			}
+			tbl.AddRow(same(o.Name), same(o.Current), same(o.Wanted), newer(o.Latest), same(o.Spec))
+			continue
+		}
+		tbl.AddRow(o.Name, current(o.Current), wanted(o.Wanted), latest(o.Latest), hd(o.Spec))
+	}
+	tbl.Print()
+	return buff.String(), nil
+}
@@ -0,0 +1,65 @@
+package outdated
+
+import (
+	"context"
+	_ "embed"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/mattn/go-isatty"
+	"github.com/vic/ntv/packages/app/help"
+	"github.com/vic/ntv/packages/app/search_args"
+	"github.com/vic/ntv/packages/backends"
+)
+
+type OutdatedArgs struct {
+	File           string `long:"file" short:"f"`
+	JSON           bool   `long:"json" short:"j"`
+	FailOnOutdated bool   `long:"fail-on-outdated"`
+	Color          bool   `long:"color" short:"C"`
+	search         *search_args.SearchArgs
+	rest           []string
+}
+
+//go:embed HELP
+var HELP string
+
+var Help = help.CmdHelp{
+	HelpTxt: HELP,
+	HelpCtx: func(name string) any {
+		return map[string]interface{}{
+			"Cmd":      name,
+			"Backends": backends.Registered(),
+		}
+	},
+}
+
+func NewOutdatedArgs() *OutdatedArgs {
+	args := OutdatedArgs{
+		File:   "flake.nix",
+		Color:  isatty.IsTerminal(os.Stdout.Fd()),
+		search: search_args.NewSearchArgs(),
+	}
+	return &args
+}
+
+func (a *OutdatedArgs) Parse(args []string) error {
+	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	if err := a.search.AddTo(parser); err != nil {
+		return err
+	}
+	rest, err := parser.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	a.rest = rest
+	return nil
+}
+
+func (a *OutdatedArgs) ParseAndRun(ctx context.Context, args []string) error {
+	err := a.Parse(args)
+	if err != nil {
+		return err
+	}
+	return a.Run(ctx)
+}
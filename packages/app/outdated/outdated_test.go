@@ -0,0 +1,72 @@
+package outdated
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func resolved(versions ...string) *search.PackageSearchResult {
+	r := &search.PackageSearchResult{Backend: &backends.NixHub{}}
+	for _, v := range versions {
+		r.Versions = append(r.Versions, &lib.Version{Name: "hello", Version: v})
+	}
+	return r
+}
+
+func TestReport_pinned_tool_with_newer_latest_is_not_outdated(t *testing.T) {
+	f := flake.New()
+	f.Tools["hello"] = flake.Tool{Name: "hello", Spec: "hello@=1.0", Version: "1.0"}
+	r := resolved("1.0", "2.0")
+	r.Selected = r.Versions[0]
+
+	report := Report(f, map[string]*search.PackageSearchResult{"hello": r}, nil)
+	assert(t, len(report) == 1, "one tool")
+	assert(t, report[0].Wanted == "1.0" && report[0].Latest == "2.0", "wanted and latest")
+	assert(t, !report[0].Outdated, "pinned tool cannot move")
+}
+
+func TestReport_outdated_when_wanted_is_newer(t *testing.T) {
+	f := flake.New()
+	f.Tools["hello"] = flake.Tool{Name: "hello", Spec: "hello@1", Version: "1.0"}
+	r := resolved("1.0", "1.1")
+	r.Selected = r.Versions[1]
+
+	report := Report(f, map[string]*search.PackageSearchResult{"hello": r}, nil)
+	assert(t, report[0].Outdated, "wanted 1.1")
+}
+
+func TestReport_older_wanted_is_not_outdated(t *testing.T) {
+	f := flake.New()
+	f.Tools["hello"] = flake.Tool{Name: "hello", Spec: "nixhub,history:hello", Version: "2.1"}
+	r := resolved("1.9", "2.0")
+	r.Selected = r.Versions[1]
+
+	report := Report(f, map[string]*search.PackageSearchResult{"hello": r}, nil)
+	assert(t, report[0].Wanted == "2.0", "wanted by the fallback backend")
+	assert(t, !report[0].Outdated, "older wanted is no upgrade")
+}
+
+func TestReport_keeps_failed_tools(t *testing.T) {
+	f := flake.New()
+	f.Tools["hello"] = flake.Tool{Name: "hello", Spec: "hello", Version: "1.0"}
+	f.Tools["cowsay"] = flake.Tool{Name: "cowsay", Spec: "cowsay", Version: "3.0", Backend: "history"}
+	r := resolved("1.0")
+	r.Selected = r.Versions[0]
+
+	report := Report(f, map[string]*search.PackageSearchResult{"hello": r}, map[string]error{"cowsay": errors.New("down")})
+	assert(t, len(report) == 2, "both tools")
+	assert(t, report[0].Name == "cowsay" && report[0].Error == "down", "failed tool with its error")
+	assert(t, report[0].Backend == "history", "failed tool backend")
+	assert(t, report[1].Name == "hello" && report[1].Error == "", "resolved tool")
+}
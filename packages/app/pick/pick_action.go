@@ -0,0 +1,211 @@
+package pick
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+	"github.com/vic/ntv/packages/search_spec"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func (a *PickArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
+	if len(a.rest) == 0 {
+		return fmt.Errorf("expected at least one package-spec to pick versions from")
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return fmt.Errorf("ntv pick needs an interactive terminal. use `ntv list` instead")
+	}
+
+	specs, err := search_spec.ParseSearchSpecs(a.rest, a.search.VersionsBackend)
+	if err != nil {
+		return err
+	}
+
+	res, err := search.PackageSearchSpecs(specs.WithNixSearch(a.search.NixSearch())).Search(ctx)
+	if err != nil {
+		return err
+	}
+	a.search.WarnTruncated(res)
+
+	p := NewPicker(res)
+	if len(p.Results) == 0 {
+		return fmt.Errorf("no versions found to pick from")
+	}
+
+	picked, err := a.pick(p)
+	if err != nil {
+		return err
+	}
+	if picked == nil {
+		return fmt.Errorf("cancelled. nothing written to %s", a.Out)
+	}
+	if len(picked) == 0 {
+		return fmt.Errorf("every package was skipped. nothing written to %s", a.Out)
+	}
+
+	if strings.HasSuffix(a.Out, ".nix") {
+		err = writeFlake(ctx, a.Out, picked)
+	} else {
+		err = writeToolVersions(a.Out, picked)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, r := range picked {
+		fmt.Println(*r.FromSearch.Spec)
+	}
+	return nil
+}
+
+// runs the picker until done, returns nil results if the user quits.
+func (a *PickArgs) pick(p *Picker) (search.PackageSearchResults, error) {
+	restore, err := makeRaw()
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	if rows := termRows(); rows > 8 {
+		p.Height = rows - 6
+	}
+
+	for {
+		draw(p.View())
+		k, err := readKey()
+		if err != nil {
+			return nil, err
+		}
+		done, quit := p.Key(k)
+		if quit {
+			return nil, nil
+		}
+		if done {
+			break
+		}
+	}
+
+	picked := search.PackageSearchResults{}
+	for i, v := range p.Chosen() {
+		if v != nil {
+			picked = append(picked, Exact(p.Results[i], v))
+		}
+	}
+	return picked, nil
+}
+
+// Exact is a copy of r having v as its only version, searched by an exact `name@=version` spec.
+func Exact(r *search.PackageSearchResult, v *lib.Version) *search.PackageSearchResult {
+	spec := ExactSpec(r, v)
+	from := *r.FromSearch
+	from.Spec = &spec
+	return &search.PackageSearchResult{
+		FromSearch:  &from,
+		Backend:     r.Backend,
+		Versions:    r.Versions,
+		Constrained: []*lib.Version{v},
+		Selected:    v,
+		Package:     r.Package,
+	}
+}
+
+// ExactSpec keeps the backend prefix of the original spec, eg: `lazamar:nixos-24.05:emacs@=29.4`
+// Wildcard queries are replaced by the found attribute.
+func ExactSpec(r *search.PackageSearchResult, v *lib.Version) string {
+	spec, query := *r.FromSearch.Spec, *r.FromSearch.Query
+	prefix := ""
+	if idx := strings.Index(spec, query); idx > 0 {
+		prefix = spec[:idx]
+	}
+	return prefix + exactQuery(r, v) + "@=" + v.Version
+}
+
+func exactQuery(r *search.PackageSearchResult, v *lib.Version) string {
+	if _, isFlake := r.Backend.(*backends.Flake); isFlake {
+		return *r.FromSearch.Query
+	}
+	if r.Package != nil && r.Package.AttrName != "" {
+		return r.Package.AttrName
+	}
+	return v.Attribute
+}
+
+func writeFlake(ctx context.Context, file string, picked search.PackageSearchResults) error {
+	if err := picked.EnsureUniquePackageNames(); err != nil {
+		return err
+	}
+	f, err := flake.Load(ctx, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = flake.New(), nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, r := range picked {
+		f.AddTool(r)
+	}
+	return f.Write(ctx, file, true)
+}
+
+func writeToolVersions(file string, picked search.PackageSearchResults) error {
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	lines := []string{}
+	for _, r := range picked {
+		lines = append(lines, ToolVersionsLine(r))
+	}
+	return os.WriteFile(file, []byte(UpdateToolVersions(string(content), lines)), 0o644)
+}
+
+// ToolVersionsLine for a picked result: `name version`, like asdf and mise expect.
+// Backend prefixes are not kept, ntv reads the line back as `name@version`.
+func ToolVersionsLine(r *search.PackageSearchResult) string {
+	return exactQuery(r, r.Selected) + " " + r.Selected.Version
+}
+
+// UpdateToolVersions replaces the lines for the same tools as the new lines, appending them.
+// Comments and other tools are kept.
+func UpdateToolVersions(content string, newLines []string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if line == "" && len(lines) == 0 {
+			continue
+		}
+		replaced := false
+		for _, newLine := range newLines {
+			if toolName(line) != "" && toolName(line) == toolName(newLine) {
+				replaced = true
+			}
+		}
+		if !replaced {
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, newLines...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// bare name of the tool on a `.tool-versions` line: `name version` or `name@version`.
+// Backend prefixes like `lazamar:nixos-24.05:name` are ignored.
+func toolName(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return ""
+	}
+	name, _, _ := strings.Cut(fields[0], "@")
+	return name[strings.LastIndex(name, ":")+1:]
+}
@@ -0,0 +1,109 @@
+package pick
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func result(spec, query string, versions ...string) *search.PackageSearchResult {
+	r := &search.PackageSearchResult{
+		FromSearch: &search.PackageSearchSpec{Spec: &spec, Query: &query},
+		Backend:    &backends.Lazamar{Channel: "nixos-24.05"},
+	}
+	for _, v := range versions {
+		r.Versions = append(r.Versions, &lib.Version{Name: "emacs", Attribute: "emacs", Version: v})
+	}
+	r.Constrained = r.Versions[:len(r.Versions)-1]
+	r.Selected = r.Constrained[len(r.Constrained)-1]
+	return r
+}
+
+func TestPicker_starts_at_selected(t *testing.T) {
+	p := NewPicker(search.PackageSearchResults{result("emacs@<30", "emacs", "28", "29", "30")})
+	assert(t, p.Cursors[0] == 1, "cursor on selected")
+	p.Key(KeyDown)
+	p.Key(KeyDown)
+	assert(t, p.Cursors[0] == 2, "cursor stops at newest")
+	done, _ := p.Key(KeyChoose)
+	assert(t, done, "choosing last package is done")
+	assert(t, p.Chosen()[0].Version == "30", "chosen")
+}
+
+func TestPicker_moves_between_packages(t *testing.T) {
+	p := NewPicker(search.PackageSearchResults{result("a", "a", "1", "2"), result("b", "b", "1", "2")})
+	done, _ := p.Key(KeyChoose)
+	assert(t, !done && p.Current == 1, "next package")
+	p.Key(KeyPrev)
+	assert(t, p.Current == 0, "previous package")
+	_, quit := p.Key(KeyQuit)
+	assert(t, quit, "quit")
+}
+
+func TestPicker_skips_packages(t *testing.T) {
+	p := NewPicker(search.PackageSearchResults{result("a", "a", "1", "2"), result("b", "b", "1", "2")})
+	done, _ := p.Key(KeySkip)
+	assert(t, !done && p.Current == 1, "skipping shows the next package")
+	assert(t, strings.Contains(p.View(), "s skip"), "skip key on help")
+	done, _ = p.Key(KeyChoose)
+	assert(t, done, "choosing last package is done")
+	chosen := p.Chosen()
+	assert(t, chosen[0] == nil && chosen[1].Version == "1", "only b chosen")
+
+	p.Key(KeyPrev)
+	assert(t, strings.Contains(p.View(), "skipped"), "skipped package is marked")
+	p.Key(KeyChoose)
+	assert(t, p.Chosen()[0] != nil, "choosing again un-skips")
+}
+
+func TestExactSpec_keeps_prefix(t *testing.T) {
+	r := result("lazamar:nixos-24.05:emacs@<30", "emacs", "28", "29", "30")
+	assert(t, ExactSpec(r, r.Versions[0]) == "lazamar:nixos-24.05:emacs@=28", ExactSpec(r, r.Versions[0]))
+}
+
+func TestExactSpec_replaces_wildcards(t *testing.T) {
+	r := result("*ema*", "*ema*", "28", "29")
+	assert(t, ExactSpec(r, r.Versions[1]) == "emacs@=29", ExactSpec(r, r.Versions[1]))
+}
+
+func TestUpdateToolVersions(t *testing.T) {
+	content := "# tools\nemacs 28 # old\nnodejs@22\n"
+	out := UpdateToolVersions(content, []string{"emacs 29", "go 1.24"})
+	assert(t, out == "# tools\nnodejs@22\nemacs 29\ngo 1.24\n", out)
+	assert(t, UpdateToolVersions("", []string{"go 1.24"}) == "go 1.24\n", "new file")
+	out = UpdateToolVersions("lazamar:nixos-24.05:emacs 28\n", []string{"emacs 29"})
+	assert(t, out == "emacs 29\n", "prefixed line replaced: "+out)
+}
+
+func TestToolVersionsLine_without_prefix(t *testing.T) {
+	r := result("lazamar:nixos-24.05:emacs", "emacs", "28", "29")
+	r = Exact(r, r.Versions[1])
+	assert(t, ToolVersionsLine(r) == "emacs 29", ToolVersionsLine(r))
+
+	content := UpdateToolVersions("emacs 28\n", []string{ToolVersionsLine(r)})
+	assert(t, content == "emacs 29\n", "one line per tool: "+content)
+}
+
+func TestParseKey(t *testing.T) {
+	assert(t, parseKey("\x1b[A") == KeyUp, "arrow up")
+	assert(t, parseKey("\r") == KeyChoose, "enter")
+	assert(t, parseKey("\x03") == KeyQuit, "ctrl-c")
+	assert(t, parseKey("s") == KeySkip, "skip")
+	assert(t, parseKey("x") == KeyNone, "unknown")
+}
+
+func TestPicker_view_without_selected(t *testing.T) {
+	r := result("emacs@<30", "emacs", "28", "29")
+	r.Selected, r.Constrained = nil, nil
+	view := NewPicker(search.PackageSearchResults{r}).View()
+	assert(t, strings.Contains(view, "> 29"), view)
+}
@@ -0,0 +1,148 @@
+package pick
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/fatih/color"
+
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+type Key uint8
+
+const (
+	KeyNone Key = iota
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyPrev
+	KeyNext
+	KeyChoose
+	KeySkip
+	KeyQuit
+)
+
+// Picker is the state of the version picker.
+// Each package has a cursor over its Versions, starting at the Selected one.
+type Picker struct {
+	Results search.PackageSearchResults
+	// Index of the package being shown.
+	Current int
+	// Index on Versions for each package.
+	Cursors []int
+	// Packages left out of the choices.
+	Skipped []bool
+	// Rows available to show versions.
+	Height int
+}
+
+func NewPicker(res search.PackageSearchResults) *Picker {
+	p := &Picker{Height: 15}
+	for _, r := range res {
+		if len(r.Versions) == 0 {
+			continue
+		}
+		cursor := slices.Index(r.Versions, r.Selected)
+		if cursor < 0 {
+			cursor = len(r.Versions) - 1
+		}
+		p.Results = append(p.Results, r)
+		p.Cursors = append(p.Cursors, cursor)
+		p.Skipped = append(p.Skipped, false)
+	}
+	return p
+}
+
+// Chosen version of each package, nil for skipped ones.
+func (p *Picker) Chosen() []*lib.Version {
+	chosen := []*lib.Version{}
+	for i, r := range p.Results {
+		if p.Skipped[i] {
+			chosen = append(chosen, nil)
+			continue
+		}
+		chosen = append(chosen, r.Versions[p.Cursors[i]])
+	}
+	return chosen
+}
+
+// Key updates the picker state.
+// Returns done when the last package was chosen or skipped, quit when the user gave up.
+func (p *Picker) Key(k Key) (done bool, quit bool) {
+	versions := len(p.Results[p.Current].Versions)
+	cursor := &p.Cursors[p.Current]
+	switch k {
+	case KeyUp:
+		*cursor = max(*cursor-1, 0)
+	case KeyDown:
+		*cursor = min(*cursor+1, versions-1)
+	case KeyPageUp:
+		*cursor = max(*cursor-p.Height, 0)
+	case KeyPageDown:
+		*cursor = min(*cursor+p.Height, versions-1)
+	case KeyHome:
+		*cursor = 0
+	case KeyEnd:
+		*cursor = versions - 1
+	case KeyPrev:
+		p.Current = max(p.Current-1, 0)
+	case KeyNext:
+		p.Current = min(p.Current+1, len(p.Results)-1)
+	case KeyChoose, KeySkip:
+		p.Skipped[p.Current] = k == KeySkip
+		if p.Current == len(p.Results)-1 {
+			return true, false
+		}
+		p.Current++
+	case KeyQuit:
+		return false, true
+	}
+	return false, false
+}
+
+// View renders the current package versions, highlighted like `ntv list` does.
+func (p *Picker) View() string {
+	r := p.Results[p.Current]
+	cursor := p.Cursors[p.Current]
+
+	hd := color.New(color.Faint).SprintfFunc()
+	bold := color.New(color.Bold).SprintfFunc()
+
+	buff := bytes.Buffer{}
+	fmt.Fprintf(&buff, "%s\n\n", hd("↑/↓ version   ←/→ package   enter choose   s skip   q quit"))
+
+	name := r.Versions[cursor].Name
+	if r.Package != nil {
+		name = r.Package.AttrName
+	}
+	skipped := ""
+	if p.Skipped[p.Current] {
+		skipped = color.New(color.FgYellow).Sprint(" skipped")
+	}
+	fmt.Fprintf(&buff, "%s %s %s %s%s\n\n", hd("[%d/%d]", p.Current+1, len(p.Results)), bold(name), hd(*r.FromSearch.Spec), hd(r.Backend.Name()), skipped)
+
+	start := min(max(cursor-p.Height/2, 0), max(len(r.Versions)-p.Height, 0))
+	end := min(start+p.Height, len(r.Versions))
+	for i := start; i < end; i++ {
+		v := r.Versions[i]
+		versionColor := color.New(color.Faint).SprintfFunc()
+		if r.Selected == v {
+			versionColor = color.New(color.FgHiGreen).SprintfFunc()
+		} else if slices.Contains(r.Constrained, v) && len(r.Constrained) < len(r.Versions) {
+			versionColor = color.New(color.FgCyan).SprintfFunc()
+		}
+		marker, installable := "  ", hd(r.Installable(v))
+		if i == cursor {
+			marker = bold("> ")
+			installable = color.New(color.ReverseVideo).Sprint(r.Installable(v))
+		}
+		fmt.Fprintf(&buff, "%s%-20s %s\n", marker, versionColor(v.Version), installable)
+	}
+	return buff.String()
+}
@@ -0,0 +1,84 @@
+package pick
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// raw terminal mode is set with stty to avoid platform specific ioctls.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// makeRaw returns a function restoring the previous terminal state.
+func makeRaw() (func(), error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	return func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		stty(state)
+	}, nil
+}
+
+// terminal rows, zero when unknown.
+func termRows() int {
+	size, err := stty("size")
+	if err != nil {
+		return 0
+	}
+	rows, _ := strconv.Atoi(strings.Fields(size + " 0")[0])
+	return rows
+}
+
+func readKey() (Key, error) {
+	buf := make([]byte, 8)
+	n, err := os.Stdin.Read(buf)
+	if err != nil {
+		return KeyNone, err
+	}
+	return parseKey(string(buf[:n])), nil
+}
+
+func parseKey(s string) Key {
+	switch s {
+	case "\x1b[A", "\x1bOA", "k":
+		return KeyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return KeyDown
+	case "\x1b[5~":
+		return KeyPageUp
+	case "\x1b[6~":
+		return KeyPageDown
+	case "\x1b[H", "\x1b[1~", "g":
+		return KeyHome
+	case "\x1b[F", "\x1b[4~", "G":
+		return KeyEnd
+	case "\x1b[D", "\x1bOD", "h", "\x7f", "\b":
+		return KeyPrev
+	case "\x1b[C", "\x1bOC", "l", "\t":
+		return KeyNext
+	case "\r", "\n", " ":
+		return KeyChoose
+	case "s":
+		return KeySkip
+	case "\x1b", "q", "\x03":
+		return KeyQuit
+	}
+	return KeyNone
+}
+
+// in raw mode, newlines do not return the cursor.
+func draw(view string) {
+	os.Stdout.WriteString("\x1b[H\x1b[2J" + strings.ReplaceAll(view, "\n", "\r\n"))
+}
@@ -0,0 +1,39 @@
+package remove
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vic/ntv/packages/flake"
+)
+
+func (a *RemoveArgs) Run(ctx context.Context) error {
+	if len(a.rest) == 0 {
+		return fmt.Errorf("expected at least one tool name to remove")
+	}
+
+	f, err := flake.Load(ctx, a.File)
+	if err != nil {
+		return err
+	}
+
+	unused, err := f.RemoveTools(a.rest...)
+	if err != nil {
+		return err
+	}
+
+	referenced, err := f.ReferencedInputs(a.File, unused)
+	if err != nil {
+		return err
+	}
+	if len(referenced) > 0 && !a.Force {
+		return fmt.Errorf("inputs %s are still referenced by flakeModule.nix or flake imports. use --force to remove them anyway", strings.Join(referenced, ", "))
+	}
+
+	for _, input := range unused {
+		f.Flake.RemoveInput(input)
+	}
+
+	return f.Write(ctx, a.File, true)
+}
@@ -0,0 +1,51 @@
+package remove
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/vic/ntv/packages/app/help"
+)
+
+type RemoveArgs struct {
+	File  string `long:"file" short:"f"`
+	Force bool   `long:"force"`
+	rest  []string
+}
+
+//go:embed HELP
+var HELP string
+
+var Help = help.CmdHelp{
+	HelpTxt: HELP,
+	HelpCtx: func(name string) any {
+		return map[string]interface{}{
+			"Cmd": name,
+		}
+	},
+}
+
+func NewRemoveArgs() *RemoveArgs {
+	return &RemoveArgs{
+		File: "flake.nix",
+	}
+}
+
+func (a *RemoveArgs) Parse(args []string) error {
+	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	rest, err := parser.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	a.rest = rest
+	return nil
+}
+
+func (a *RemoveArgs) ParseAndRun(ctx context.Context, args []string) error {
+	err := a.Parse(args)
+	if err != nil {
+		return err
+	}
+	return a.Run(ctx)
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+
+	"github.com/vic/ntv/packages/backends/nixsearch"
+	"github.com/vic/ntv/packages/cache"
+)
+
+type Package struct {
+	Attr        string   `json:"attr"`
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	Programs    []string `json:"programs"`
+	Licenses    []string `json:"licenses"`
+}
+
+func (a *SearchArgs) Run(ctx context.Context) error {
+	if len(a.rest) == 0 {
+		return fmt.Errorf("expected a search query. try `ntv search --help`")
+	}
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
+	nixSearch := a.search.NixSearch()
+	found, err := FindPackages(ctx, nixSearch.Channel, nixSearch.Limit, strings.Join(a.rest, " "))
+	if err != nil {
+		return err
+	}
+
+	out, err := a.PackagesOut(found)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// FindPackages matching query by attribute name, program or description.
+func FindPackages(ctx context.Context, channel string, limit int, query string) ([]Package, error) {
+	key := cache.Key("nixsearch", channel, "text", strconv.Itoa(limit), query)
+	pkgs, err := cache.Fetch(cache.Default, key, func() ([]nixsearch.Package, error) {
+		return nixsearch.FindPackagesWithText(ctx, channel, limit, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	found := []Package{}
+	for _, p := range pkgs {
+		found = append(found, AsPackage(p))
+	}
+	return found, nil
+}
+
+func AsPackage(p nixsearch.Package) Package {
+	licenses := []string{}
+	for _, l := range p.Licenses {
+		licenses = append(licenses, l.FullName)
+	}
+	programs := p.Programs
+	if programs == nil {
+		programs = []string{}
+	}
+	return Package{
+		Attr:        p.AttrName,
+		Version:     p.Version,
+		Description: p.Description,
+		Programs:    programs,
+		Licenses:    licenses,
+	}
+}
+
+func (a *SearchArgs) PackagesOut(pkgs []Package) (string, error) {
+	if a.JSON {
+		jsonBytes, err := json.MarshalIndent(pkgs, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(jsonBytes) + "\n", nil
+	}
+
+	color.NoColor = !a.Color
+
+	hd := color.New(color.Faint).SprintfFunc()
+	attr := color.New(color.FgHiBlue).SprintfFunc()
+	version := color.New(color.FgHiGreen).SprintfFunc()
+	faint := color.New(color.Faint).SprintfFunc()
+
+	buff := bytes.Buffer{}
+	tbl := table.New(hd("Attr"), hd("Version"), hd("Programs"), hd("License"), hd("Description")).WithWriter(&buff)
+	for _, p := range pkgs {
+		tbl.AddRow(attr(p.Attr), version(p.Version), strings.Join(p.Programs, " "), faint(strings.Join(p.Licenses, ", ")), p.Description)
+	}
+	tbl.Print()
+	return buff.String(), nil
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/mattn/go-isatty"
+	"github.com/vic/ntv/packages/app/help"
+	"github.com/vic/ntv/packages/app/search_args"
+)
+
+type SearchArgs struct {
+	JSON    bool            `long:"json" short:"j"`
+	Color   bool            `long:"color" short:"C"`
+	OnLimit func(int) error `long:"limit"`
+	search  *search_args.SearchArgs
+	rest    []string
+}
+
+//go:embed HELP
+var HELP string
+
+var Help = help.CmdHelp{
+	HelpTxt: HELP,
+	HelpCtx: func(name string) any {
+		return map[string]interface{}{
+			"Cmd": name,
+		}
+	},
+}
+
+func NewSearchArgs() *SearchArgs {
+	args := SearchArgs{
+		Color:  isatty.IsTerminal(os.Stdout.Fd()),
+		search: search_args.NewSearchArgs(),
+	}
+	// no versions are searched, so `--channel` is the search.nixos.org one instead of lazamar's.
+	args.search.OnChannel = func(channel string) error {
+		args.search.SearchChannel = channel
+		return nil
+	}
+	args.OnLimit = func(limit int) error {
+		return args.search.OnSearchLimit(limit)
+	}
+	return &args
+}
+
+func (a *SearchArgs) Parse(args []string) error {
+	parser := flags.NewParser(a, flags.AllowBoolValues|flags.IgnoreUnknown)
+	if err := a.search.AddTo(parser); err != nil {
+		return err
+	}
+	rest, err := parser.ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	a.rest = rest
+	return nil
+}
+
+func (a *SearchArgs) ParseAndRun(ctx context.Context, args []string) error {
+	err := a.Parse(args)
+	if err != nil {
+		return err
+	}
+	return a.Run(ctx)
+}
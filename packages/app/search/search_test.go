@@ -0,0 +1,48 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_shared_search_options(t *testing.T) {
+	a := NewSearchArgs()
+	assertNoErr(t, a.Parse([]string{"--search-channel", "24.11", "--search-limit", "5", "--timeout", "3s", "json", "processor"}))
+	assert(t, a.search.NixSearch().Channel == "24.11", "search channel")
+	assert(t, a.search.NixSearch().Limit == 5, "search limit")
+	assert(t, a.search.Timeout == 3*time.Second, "timeout")
+	assert(t, len(a.rest) == 2, "query words")
+}
+
+func TestParse_rejects_non_positive_search_limit(t *testing.T) {
+	for _, limit := range []string{"0", "-1"} {
+		err := NewSearchArgs().Parse([]string{"--search-limit", limit, "hello"})
+		assert(t, err != nil, "should reject --search-limit "+limit)
+	}
+}
+
+func TestParse_channel_and_limit_aliases(t *testing.T) {
+	a := NewSearchArgs()
+	assertNoErr(t, a.Parse([]string{"--channel", "24.11", "--limit", "5", "ripgrep"}))
+	assert(t, a.search.NixSearch().Channel == "24.11", "--channel is the search channel")
+	assert(t, a.search.NixSearch().Limit == 5, "--limit is the search limit")
+	assert(t, len(a.rest) == 1 && a.rest[0] == "ripgrep", "query words")
+
+	a = NewSearchArgs()
+	assertNoErr(t, a.Parse([]string{"-c", "24.05", "hello"}))
+	assert(t, a.search.NixSearch().Channel == "24.05", "-c is the search channel")
+
+	err := NewSearchArgs().Parse([]string{"--limit", "0", "hello"})
+	assert(t, err != nil, "should reject --limit 0")
+}
@@ -0,0 +1,162 @@
+package search_args
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/cache"
+	"github.com/vic/ntv/packages/nix"
+	"github.com/vic/ntv/packages/search"
+	"github.com/vic/ntv/packages/search_spec"
+	"github.com/vic/ntv/packages/throttle"
+)
+
+// Search options shared by commands resolving package-specs.
+type SearchArgs struct {
+	OnBackend       func(string) error `long:"backend" short:"b"`
+	OnChannel       func(string) error `long:"channel" short:"c"`
+	OnNixpkgsRepo   func(string) error `long:"nixpkgs-repo"`
+	OnNoCache       func()             `long:"no-cache"`
+	OnRefresh       func()             `long:"refresh"`
+	OnCacheTTL      func(string) error `long:"cache-ttl"`
+	OnOffline       func()             `long:"offline"`
+	Timeout         time.Duration      `long:"timeout"`
+	Optimize        bool               `long:"optimize" short:"O"`
+	OnJobs          func(int)          `long:"jobs" short:"J"`
+	OnRateLimit     func(float64)      `long:"rate-limit"`
+	OnRetries       func(int)          `long:"retries"`
+	SearchChannel   string             `long:"search-channel"`
+	OnSearchLimit   func(int) error    `long:"search-limit"`
+	SearchLimit     int
+	VersionsBackend backends.VersionsBackend
+	Cache           *cache.Cache
+}
+
+func NewSearchArgs() *SearchArgs {
+	args := SearchArgs{
+		VersionsBackend: &backends.NixHub{},
+		Cache:           cache.Default,
+		SearchChannel:   search_spec.DefaultNixSearch.Channel,
+		SearchLimit:     search_spec.DefaultNixSearch.Limit,
+	}
+	if channel := os.Getenv("NTV_SEARCH_CHANNEL"); channel != "" {
+		args.SearchChannel = channel
+	}
+	if limit, err := strconv.Atoi(os.Getenv("NTV_SEARCH_LIMIT")); err == nil && limit > 0 {
+		args.SearchLimit = limit
+	}
+	args.OnBackend = func(name string) error {
+		b, err := backends.NewChain(name)
+		if err != nil {
+			return err
+		}
+		args.VersionsBackend = b
+		return nil
+	}
+	args.OnSearchLimit = func(limit int) error {
+		if limit <= 0 {
+			return fmt.Errorf("--search-limit must be a positive number, got %d", limit)
+		}
+		args.SearchLimit = limit
+		return nil
+	}
+	args.OnChannel = func(channel string) error {
+		return args.OnBackend("lazamar:" + channel)
+	}
+	args.OnNixpkgsRepo = func(repo string) error {
+		return args.OnBackend("git:" + repo)
+	}
+	args.OnNoCache = func() {
+		args.Cache.Read = false
+		args.Cache.Write = false
+	}
+	args.OnRefresh = func() {
+		args.Cache.Read = false
+	}
+	args.OnCacheTTL = func(ttl string) error {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return err
+		}
+		args.Cache.TTL = d
+		return nil
+	}
+	args.OnJobs = func(n int) {
+		throttle.SetJobs(n)
+	}
+	args.OnRateLimit = func(rate float64) {
+		throttle.Rate = rate
+	}
+	args.OnRetries = func(n int) {
+		throttle.MaxRetries = n
+	}
+	args.OnOffline = func() {
+		args.Cache.Offline = true
+		nix.Offline = true
+	}
+	if offline, _ := strconv.ParseBool(os.Getenv("NTV_OFFLINE")); offline {
+		args.OnOffline()
+	}
+	return &args
+}
+
+// NixSearch options for finding packages on search.nixos.org
+func (a *SearchArgs) NixSearch() search_spec.NixSearch {
+	return search_spec.NixSearch{Channel: a.SearchChannel, Limit: a.SearchLimit}
+}
+
+// WarnTruncated tells on stderr which package-specs matched more packages than the `--search-limit`.
+func (a *SearchArgs) WarnTruncated(res search.PackageSearchResults) {
+	for _, s := range res.TruncatedSpecs() {
+		fmt.Fprintf(os.Stderr, "warning: only the first %d packages matching `%s` on channel `%s` were searched. use --search-limit for more\n", s.NixSearch.Limit, *s.Spec, s.NixSearch.Channel)
+	}
+}
+
+// WithTimeout limits ctx to the `--timeout` duration, if any.
+func (a *SearchArgs) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.Timeout > 0 {
+		return context.WithTimeout(ctx, a.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// AddTo registers the search options on parser.
+// Each registered backend gets its own `--name` flag.
+func (a *SearchArgs) AddTo(parser *flags.Parser) error {
+	group, err := parser.AddGroup("Search", "", a)
+	if err != nil {
+		return err
+	}
+	for _, r := range backends.Registered() {
+		if r.Prefix == "" {
+			continue
+		}
+		option := &flags.Option{
+			LongName:    r.Name,
+			ShortName:   r.Short,
+			Description: r.Description,
+		}
+		group.AddOption(option, func() error {
+			return a.selectBackend(r)
+		})
+	}
+	return nil
+}
+
+// keeps the current backend options (eg, a lazamar channel) if already selected.
+func (a *SearchArgs) selectBackend(r *backends.Registration) error {
+	if a.VersionsBackend != nil && a.VersionsBackend.Prefix() == r.Prefix {
+		return nil
+	}
+	b, err := r.New("")
+	if err != nil {
+		return err
+	}
+	a.VersionsBackend = b
+	return nil
+}
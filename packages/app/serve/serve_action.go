@@ -0,0 +1,254 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vic/ntv/packages/app/new"
+	appsearch "github.com/vic/ntv/packages/app/search"
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+	"github.com/vic/ntv/packages/search_spec"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+// Versions of a package found by a backend.
+type Versions struct {
+	Attr        string         `json:"attr"`
+	Backend     string         `json:"backend"`
+	Versions    []*lib.Version `json:"versions"`
+	Constrained []*lib.Version `json:"constrained"`
+	Selected    *lib.Version   `json:"selected"`
+}
+
+// a request error, answered with 400 Bad Request.
+type badRequest struct{ error }
+
+const (
+	// Largest request body accepted.
+	MaxBodyBytes = 64 << 10
+	// Most package-specs on a single request.
+	MaxSpecs = 100
+	// Largest `limit` on /search.
+	MaxSearchLimit = 200
+	// Longest time to read request headers.
+	ReadHeaderTimeout = 10 * time.Second
+)
+
+func (a *ServeArgs) Server() *http.Server {
+	return &http.Server{Addr: a.Listen, Handler: a.Handler(), ReadHeaderTimeout: ReadHeaderTimeout}
+}
+
+func (a *ServeArgs) Run(ctx context.Context) error {
+	if !RemoteBackend(a.search.VersionsBackend) {
+		return fmt.Errorf("ntv serve cannot use the `%s` backend. use nixhub, lazamar or history", a.search.VersionsBackend.Name())
+	}
+	server := a.Server()
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	log.Printf("ntv serving on %s", a.Listen)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Handler for the read-only JSON API. Nothing is written to disk other than cached responses.
+func (a *ServeArgs) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", a.handle(a.searchPackages))
+	mux.HandleFunc("GET /versions/{backend}/{attr}", a.handle(a.versions))
+	mux.HandleFunc("POST /resolve", a.handle(a.resolve))
+	mux.HandleFunc("POST /flake", a.handle(a.flake))
+	return mux
+}
+
+// handle writes the value returned by fn as JSON, or a string as plain text.
+func (a *ServeArgs) handle(fn func(ctx context.Context, r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := a.search.WithTimeout(r.Context())
+		defer cancel()
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+
+		v, err := fn(ctx, r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.As(err, &badRequest{}) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		if code, isText := v.(string); isText {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, code)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// GET /search?q=QUERY&channel=CHAN&limit=N
+func (a *ServeArgs) searchPackages(ctx context.Context, r *http.Request) (any, error) {
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		return nil, badRequest{fmt.Errorf("missing query parameter `q`")}
+	}
+	nixSearch := a.search.NixSearch()
+	if channel := q.Get("channel"); channel != "" {
+		nixSearch.Channel = channel
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > MaxSearchLimit {
+			return nil, badRequest{fmt.Errorf("invalid limit `%s`, expected 1 to %d", limit, MaxSearchLimit)}
+		}
+		nixSearch.Limit = n
+	}
+	return appsearch.FindPackages(ctx, nixSearch.Channel, nixSearch.Limit, q.Get("q"))
+}
+
+// GET /versions/{backend}/{attr}?constraint=C
+func (a *ServeArgs) versions(ctx context.Context, r *http.Request) (any, error) {
+	backend, err := backends.NewChain(r.PathValue("backend"))
+	if err != nil {
+		return nil, badRequest{err}
+	}
+	spec := r.PathValue("attr")
+	if constraint := r.URL.Query().Get("constraint"); constraint != "" {
+		spec += "@" + constraint
+	}
+	res, err := a.searchSpecs(ctx, []string{spec}, backend)
+	if err != nil {
+		return nil, err
+	}
+	found := []Versions{}
+	for _, r := range res {
+		attr := r.FromSearch.Query
+		if r.Package != nil {
+			attr = &r.Package.AttrName
+		}
+		found = append(found, Versions{
+			Attr:        *attr,
+			Backend:     r.Backend.Name(),
+			Versions:    r.Versions,
+			Constrained: r.Constrained,
+			Selected:    r.Selected,
+		})
+	}
+	return found, nil
+}
+
+// POST /resolve with a JSON list of package-specs.
+func (a *ServeArgs) resolve(ctx context.Context, r *http.Request) (any, error) {
+	res, err := a.resolveBody(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	tools := []flake.Tool{}
+	for _, r := range res {
+		tools = append(tools, flake.AsTool(r))
+	}
+	return tools, nil
+}
+
+// POST /flake with a JSON list of package-specs.
+func (a *ServeArgs) flake(ctx context.Context, r *http.Request) (any, error) {
+	res, err := a.resolveBody(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	// remote clients never make ntv run nix, not even to format the flake.
+	return new.FlakeCode(ctx, flake.New(), res, false)
+}
+
+func (a *ServeArgs) resolveBody(ctx context.Context, r *http.Request) (search.PackageSearchResults, error) {
+	var specs []string
+	if err := json.NewDecoder(r.Body).Decode(&specs); err != nil {
+		return nil, badRequest{fmt.Errorf("expected a JSON list of package-specs: %v", err)}
+	}
+	if len(specs) == 0 || len(specs) > MaxSpecs {
+		return nil, badRequest{fmt.Errorf("expected 1 to %d package-specs", MaxSpecs)}
+	}
+	res, err := a.searchSpecs(ctx, specs, a.search.VersionsBackend)
+	if err != nil {
+		return nil, err
+	}
+	if err := res.EnsureOneSelected(); err != nil {
+		return nil, badRequest{err}
+	}
+	if err := res.EnsureUniquePackageNames(); err != nil {
+		return nil, badRequest{err}
+	}
+	return res, nil
+}
+
+func (a *ServeArgs) searchSpecs(ctx context.Context, specs []string, backend backends.VersionsBackend) (search.PackageSearchResults, error) {
+	parsed, err := search_spec.ParseRemoteSearchSpecs(specs, backend)
+	if err != nil {
+		return nil, badRequest{err}
+	}
+	for _, s := range parsed {
+		if err := checkRemote(s); err != nil {
+			return nil, badRequest{err}
+		}
+	}
+	res, err := search.PackageSearchSpecs(parsed.WithNixSearch(a.search.NixSearch())).Search(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if a.search.Optimize {
+		res.MinimizeRevisions()
+	}
+	return res, nil
+}
+
+// clients can only search registries, never make ntv read local files or run git and nix.
+func checkRemote(s *search_spec.PackageSearchSpec) error {
+	if !RemoteBackend(s.VersionsBackend) {
+		return fmt.Errorf("backend `%s` of `%s` is not available. use nixhub, lazamar or history", s.VersionsBackend.Name(), *s.Spec)
+	}
+	if s.VersionConstraint == nil {
+		return nil
+	}
+	if strings.ContainsRune(*s.VersionConstraint, '/') {
+		return fmt.Errorf("version constraints cannot be read from files, got `%s`", *s.Spec)
+	}
+	if _, err := lib.ConstraintBy(nil, *s.VersionConstraint); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoteBackend tells if b only queries remote version registries.
+func RemoteBackend(b backends.VersionsBackend) bool {
+	switch b := b.(type) {
+	case backends.Chain:
+		return !slices.ContainsFunc(b, func(b backends.VersionsBackend) bool { return !RemoteBackend(b) })
+	case backends.Merged:
+		return !slices.ContainsFunc(b, func(b backends.VersionsBackend) bool { return !RemoteBackend(b) })
+	case *backends.NixHub, *backends.Lazamar, *backends.History:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func request(method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	NewServeArgs().Handler().ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestSearch_requires_query(t *testing.T) {
+	w := request(http.MethodGet, "/search", "")
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+	assert(t, strings.Contains(w.Body.String(), `"error"`), "json error")
+}
+
+func TestVersions_unknown_backend(t *testing.T) {
+	w := request(http.MethodGet, "/versions/nope/hello", "")
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+	assert(t, strings.Contains(w.Body.String(), "unknown versions backend"), w.Body.String())
+}
+
+func TestResolve_expects_spec_list(t *testing.T) {
+	w := request(http.MethodPost, "/resolve", `{"hello": 1}`)
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+	w = request(http.MethodPost, "/flake", `[]`)
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+}
+
+func TestResolve_only_post(t *testing.T) {
+	w := request(http.MethodGet, "/resolve", "")
+	assert(t, w.Code == http.StatusMethodNotAllowed, w.Body.String())
+}
+
+func TestResolve_never_reads_constraint_files(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(file, []byte("top-secret"), 0o600)
+	w := request(http.MethodPost, "/resolve", `["hello@`+file+`"]`)
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+	assert(t, !strings.Contains(w.Body.String(), "top-secret"), "file contents leaked")
+
+	w = request(http.MethodGet, "/versions/nixhub/hello?constraint="+url.QueryEscape(file), "")
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+	assert(t, !strings.Contains(w.Body.String(), "top-secret"), "file contents leaked")
+}
+
+func TestResolve_rejects_invalid_constraints(t *testing.T) {
+	w := request(http.MethodPost, "/resolve", `["hello@flake.nix"]`)
+	assert(t, w.Code == http.StatusBadRequest, w.Body.String())
+}
+
+func TestResolve_only_registry_backends(t *testing.T) {
+	for _, spec := range []string{"git:hello", "system:hello", "github:owner/repo#hello", "./local#hello", "flake:nixpkgs#hello"} {
+		w := request(http.MethodPost, "/resolve", `["`+spec+`"]`)
+		assert(t, w.Code == http.StatusBadRequest, spec+": "+w.Body.String())
+	}
+	for _, backend := range []string{"git:%2Fsrc%2Fnixpkgs", "system", "nixhub,system", "history+git"} {
+		w := request(http.MethodGet, "/versions/"+backend+"/hello", "")
+		assert(t, w.Code == http.StatusBadRequest, backend+": "+w.Body.String())
+	}
+}
+
+func TestResolve_limits_body(t *testing.T) {
+	w := request(http.MethodPost, "/resolve", `["`+strings.Repeat("a", MaxBodyBytes)+`"]`)
+	assert(t, w.Code == http.StatusBadRequest, "body too large")
+
+	specs := strings.Repeat(`"hello",`, MaxSpecs) + `"hello"`
+	w = request(http.MethodPost, "/resolve", "["+specs+"]")
+	assert(t, w.Code == http.StatusBadRequest, "too many specs")
+}
+
+func TestSearch_limits_limit(t *testing.T) {
+	for _, limit := range []string{"0", "-1", "1000", "x"} {
+		w := request(http.MethodGet, "/search?q=hello&limit="+limit, "")
+		assert(t, w.Code == http.StatusBadRequest, "limit "+limit)
+	}
+}
+
+func TestParse_listen(t *testing.T) {
+	a := NewServeArgs()
+	err := a.Parse([]string{"--listen", "127.0.0.1:18089"})
+	assert(t, err == nil && a.Listen == "127.0.0.1:18089", "listen address")
+
+	a = NewServeArgs()
+	a.Parse([]string{"-l"})
+	assert(t, a.Listen == ":8080" && a.search.VersionsBackend.Name() == "lazamar:nixpkgs-unstable", "-l is lazamar")
+}
+
+func TestRun_rejects_local_default_backend(t *testing.T) {
+	a := NewServeArgs()
+	assert(t, a.Parse([]string{"--backend", "system"}) == nil, "parse")
+	assert(t, a.Run(context.Background()) != nil, "system backend refused")
+}
+
+func TestServer_times_out_reading_headers(t *testing.T) {
+	assert(t, NewServeArgs().Server().ReadHeaderTimeout == ReadHeaderTimeout, "read header timeout")
+}
@@ -0,0 +1,114 @@
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+
+	"github.com/vic/ntv/packages/flake"
+	"github.com/vic/ntv/packages/search"
+)
+
+func (a *UpgradeArgs) Run(ctx context.Context) error {
+	ctx, cancel := a.search.WithTimeout(ctx)
+	defer cancel()
+
+	f, err := flake.Load(ctx, a.File)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := f.Resolve(ctx, a.rest, a.search.VersionsBackend, a.search.NixSearch())
+	if err != nil {
+		return err
+	}
+	a.search.WarnTruncated(slices.Collect(maps.Values(resolved)))
+
+	if a.search.Optimize {
+		res := search.PackageSearchResults{}
+		for _, r := range resolved {
+			res = append(res, r)
+		}
+		res.MinimizeRevisions()
+	}
+
+	upgrades := a.upgrades(f, resolved)
+	fmt.Print(a.DiffOut(f, resolved))
+
+	if a.DryRun || len(upgrades) == 0 {
+		return nil
+	}
+
+	unused := []string{}
+	for _, name := range upgrades {
+		removed, err := f.RemoveTools(name)
+		if err != nil {
+			return err
+		}
+		unused = append(unused, removed...)
+		f.AddTool(resolved[name])
+	}
+
+	// keep inputs re-used by upgraded tools or referenced by hand written code.
+	unused = slices.DeleteFunc(unused, f.InputUsed)
+	referenced, err := f.ReferencedInputs(a.File, unused)
+	if err != nil {
+		return err
+	}
+	for _, input := range unused {
+		if !slices.Contains(referenced, input) {
+			f.Flake.RemoveInput(input)
+		}
+	}
+
+	return f.Write(ctx, a.File, true)
+}
+
+// names of the tools whose version or installable changed.
+func (a *UpgradeArgs) upgrades(f *flake.Context, resolved map[string]*search.PackageSearchResult) []string {
+	names := []string{}
+	for _, name := range sortedNames(resolved) {
+		old, next := f.Tools[name], flake.AsTool(resolved[name])
+		if old.Version != next.Version || old.Installable != next.Installable {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func sortedNames(resolved map[string]*search.PackageSearchResult) []string {
+	names := []string{}
+	for name := range resolved {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DiffOut shows the current and upgraded version of each tool.
+func (a *UpgradeArgs) DiffOut(f *flake.Context, resolved map[string]*search.PackageSearchResult) string {
+	color.NoColor = !a.Color
+
+	hd := color.New(color.Faint).SprintfFunc()
+	same := color.New(color.Faint).SprintfFunc()
+	old := color.New(color.FgRed).SprintfFunc()
+	upgraded := color.New(color.FgHiGreen).SprintfFunc()
+
+	buff := bytes.Buffer{}
+	tbl := table.New(hd("Name"), hd("Version"), hd("Upgrade"), hd("NixInstallable")).WithWriter(&buff)
+	for _, name := range sortedNames(resolved) {
+		tool, next := f.Tools[name], flake.AsTool(resolved[name])
+		if tool.Version == next.Version && tool.Installable == next.Installable {
+			tbl.AddRow(same(name), same(tool.Version), same(next.Version), same(tool.Installable))
+			continue
+		}
+		tbl.AddRow(name, old(tool.Version), upgraded(next.Version), upgraded(next.Installable))
+	}
+	tbl.Print()
+	return buff.String()
+}
@@ -0,0 +1,26 @@
+package upgrade
+
+import "testing"
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_dry_run(t *testing.T) {
+	a := NewUpgradeArgs()
+	assertNoErr(t, a.Parse([]string{"--dry-run"}))
+	assert(t, a.DryRun, "--dry-run sets DryRun")
+
+	a = NewUpgradeArgs()
+	assertNoErr(t, a.Parse([]string{"-n", "hello"}))
+	assert(t, !a.DryRun, "-n selects the nixhub backend")
+	assert(t, a.search.VersionsBackend.Name() == "nixhub", "nixhub backend")
+	assert(t, len(a.rest) == 1 && a.rest[0] == "hello", "tool names")
+}
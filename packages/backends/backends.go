@@ -0,0 +1,301 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+// A VersionsBackend knows how to list the versions of a package.
+type VersionsBackend interface {
+	// Name shown to users, eg. on the VerBackend column: `lazamar:nixos-24.05`
+	Name() string
+	// Prefix used on package-specs to select this backend: `lazamar:`
+	Prefix() string
+	Search(ctx context.Context, attr string) ([]*lib.Version, error)
+}
+
+// Remote backends fetch versions from a network service.
+// Their responses are cached on disk.
+type Remote interface {
+	VersionsBackend
+	Host() string
+}
+
+type Registration struct {
+	// Name of the backend. Also used as the `--name` cli flag.
+	Name string
+	// Short cli flag, zero for none.
+	Short rune
+	// Description shown on command help.
+	Description string
+	// Prefix used on package-specs. Empty when the backend cannot be selected by prefix.
+	Prefix string
+	// New creates a backend. arg is the text following `name:` on `--backend name:arg`.
+	New func(arg string) (VersionsBackend, error)
+	// Parse receives a package-spec query with Prefix already removed
+	// and returns the backend and the remaining query.
+	// When nil, the whole query is the attribute and New("") is used.
+	Parse func(query string, defaultBackend VersionsBackend) (VersionsBackend, string, error)
+}
+
+var registry = []*Registration{}
+
+// Register makes a backend available to package-specs and cli flags.
+// Registering an existing name replaces the previous registration.
+func Register(r *Registration) {
+	for i, x := range registry {
+		if x.Name == r.Name {
+			registry[i] = r
+			return
+		}
+	}
+	registry = append(registry, r)
+}
+
+// Registered backends in registration order.
+func Registered() []*Registration {
+	return registry
+}
+
+func Lookup(name string) *Registration {
+	for _, r := range registry {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Chain is an ordered list of backends, each one tried when the previous fails or finds nothing.
+type Chain []VersionsBackend
+
+func (c Chain) Name() string {
+	names := []string{}
+	for _, b := range c {
+		names = append(names, b.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Prefix() string {
+	if len(c) == 0 {
+		return ""
+	}
+	return c[0].Prefix()
+}
+
+// Search returns the versions from the first backend not failing nor empty.
+func (c Chain) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	var found []*lib.Version
+	err := c.Fallback(ctx, func(b VersionsBackend) (bool, error) {
+		versions, err := b.Search(ctx, attr)
+		if err != nil {
+			return false, err
+		}
+		found = versions
+		return len(versions) > 0, nil
+	})
+	return found, err
+}
+
+// Fallback calls try with each backend until one of them is done.
+// A backend failing or not done falls back to the next one.
+// When no backend is done, the errors of the failed ones are returned.
+func (c Chain) Fallback(ctx context.Context, try func(b VersionsBackend) (done bool, err error)) error {
+	if len(c) == 0 {
+		return errors.New("empty versions backend chain")
+	}
+	var errs []error
+	for _, b := range c {
+		done, err := try(b)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if done {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Merged backends are all queried and their versions merged.
+type Merged []VersionsBackend
+
+func (m Merged) Name() string {
+	names := []string{}
+	for _, b := range m {
+		names = append(names, b.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (m Merged) Prefix() string {
+	if len(m) == 0 {
+		return ""
+	}
+	return m[0].Prefix()
+}
+
+// Search fails only when every backend fails.
+func (m Merged) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	return m.SearchWith(ctx, attr, func(ctx context.Context, b VersionsBackend, attr string) ([]*lib.Version, error) {
+		return b.Search(ctx, attr)
+	})
+}
+
+// SearchWith queries every backend concurrently using search, eg. to cache each response.
+func (m Merged) SearchWith(ctx context.Context, attr string, search func(ctx context.Context, b VersionsBackend, attr string) ([]*lib.Version, error)) ([]*lib.Version, error) {
+	lists := make([][]*lib.Version, len(m))
+	errs := make([]error, len(m))
+	var wg sync.WaitGroup
+	for i, b := range m {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], errs[i] = search(ctx, b, attr)
+		}()
+	}
+	wg.Wait()
+	if !slices.Contains(errs, nil) {
+		return nil, errors.Join(errs...)
+	}
+	return lib.Merge(lists...), nil
+}
+
+// Each backend on a Chain, or just b.
+func Each(b VersionsBackend) []VersionsBackend {
+	if c, ok := b.(Chain); ok {
+		return c
+	}
+	return []VersionsBackend{b}
+}
+
+// NewChain creates backends from a comma separated list. eg: `nixhub,lazamar:nixos-24.05`
+// Backends joined by `+` are Merged. eg: `nixhub+history`
+func NewChain(names string) (VersionsBackend, error) {
+	chain := Chain{}
+	for _, name := range strings.Split(names, ",") {
+		b, err := newMerged(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, b)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+func newMerged(names string) (VersionsBackend, error) {
+	merged := Merged{}
+	for _, name := range strings.Split(names, "+") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty versions backend name on `%s`", names)
+		}
+		b, err := New(name)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, b)
+	}
+	if len(merged) == 1 {
+		return merged[0], nil
+	}
+	return merged, nil
+}
+
+// Spec of b that NewChain creates it back from.
+// Unlike Name, it keeps the arguments of every backend. eg: `git:/path/to/nixpkgs`
+func Spec(b VersionsBackend) string {
+	names := []string{}
+	switch b := b.(type) {
+	case Chain:
+		for _, x := range b {
+			names = append(names, Spec(x))
+		}
+		return strings.Join(names, ",")
+	case Merged:
+		for _, x := range b {
+			names = append(names, Spec(x))
+		}
+		return strings.Join(names, "+")
+	case *Git:
+		return "git:" + b.Repo
+	case *Flake:
+		return "flake:" + b.Installable
+	}
+	return b.Name()
+}
+
+// New creates a backend from a `name` or `name:arg` string. eg: `lazamar:nixos-24.05`
+func New(nameAndArg string) (VersionsBackend, error) {
+	name, arg, _ := strings.Cut(nameAndArg, ":")
+	r := Lookup(name)
+	if r == nil {
+		return nil, fmt.Errorf("unknown versions backend `%s`. known backends are: %s", name, strings.Join(Names(), ", "))
+	}
+	return r.New(arg)
+}
+
+func Names() []string {
+	names := []string{}
+	for _, r := range registry {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// FromQuery finds the backend whose prefix starts the given package-spec query.
+// Returns a nil backend and the original query when no prefix matched.
+//
+// A fallback chain can be given as `nixhub|history|lazamar:package`,
+// the last backend prefixing the package is optional.
+func FromQuery(query string, defaultBackend VersionsBackend) (VersionsBackend, string, error) {
+	if !strings.Contains(query, "|") {
+		return fromPrefix(query, defaultBackend)
+	}
+	parts := strings.Split(query, "|")
+	chain := Chain{}
+	for _, name := range parts[:len(parts)-1] {
+		b, err := New(name)
+		if err != nil {
+			return nil, query, err
+		}
+		chain = append(chain, b)
+	}
+	last, query, err := fromPrefix(parts[len(parts)-1], defaultBackend)
+	if err != nil {
+		return nil, query, err
+	}
+	if last != nil {
+		chain = append(chain, last)
+	}
+	return chain, query, nil
+}
+
+func fromPrefix(query string, defaultBackend VersionsBackend) (VersionsBackend, string, error) {
+	for _, r := range registry {
+		if r.Prefix == "" || !strings.HasPrefix(query, r.Prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(query, r.Prefix)
+		if r.Parse != nil {
+			return r.Parse(rest, defaultBackend)
+		}
+		b, err := r.New("")
+		return b, rest, err
+	}
+	return nil, query, nil
+}
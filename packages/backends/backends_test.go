@@ -0,0 +1,105 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type fake struct {
+	name     string
+	versions []*lib.Version
+	err      error
+	calls    int
+}
+
+func (f *fake) Name() string   { return f.name }
+func (f *fake) Prefix() string { return f.name + ":" }
+func (f *fake) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	f.calls++
+	return f.versions, f.err
+}
+
+func TestChain_falls_back_on_error(t *testing.T) {
+	failing := &fake{name: "a", err: errors.New("down")}
+	ok := &fake{name: "b", versions: []*lib.Version{{Version: "1.0"}}}
+	versions, err := Chain{failing, ok}.Search(context.Background(), "hello")
+	assertNoErr(t, err)
+	assert(t, len(versions) == 1, "versions from the second backend")
+}
+
+func TestChain_falls_back_on_empty(t *testing.T) {
+	empty := &fake{name: "a"}
+	ok := &fake{name: "b", versions: []*lib.Version{{Version: "1.0"}}}
+	versions, err := Chain{empty, ok}.Search(context.Background(), "hello")
+	assertNoErr(t, err)
+	assert(t, len(versions) == 1, "versions from the second backend")
+}
+
+func TestChain_stops_on_first_found(t *testing.T) {
+	ok := &fake{name: "a", versions: []*lib.Version{{Version: "1.0"}}}
+	next := &fake{name: "b"}
+	_, err := Chain{ok, next}.Search(context.Background(), "hello")
+	assertNoErr(t, err)
+	assert(t, next.calls == 0, "second backend should not be searched")
+}
+
+func TestChain_fails_when_none_found(t *testing.T) {
+	empty := &fake{name: "a"}
+	failing := &fake{name: "b", err: errors.New("down")}
+	_, err := Chain{empty, failing}.Search(context.Background(), "hello")
+	assert(t, err != nil && err.Error() == "down", "error of the failed backend")
+
+	versions, err := Chain{empty, &fake{name: "c"}}.Search(context.Background(), "hello")
+	assertNoErr(t, err)
+	assert(t, len(versions) == 0, "no versions")
+
+	_, err = Chain{failing, &fake{name: "c", err: errors.New("gone")}}.Search(context.Background(), "hello")
+	assert(t, err != nil && err.Error() == "down\ngone", "errors of every backend")
+}
+
+func TestChain_empty(t *testing.T) {
+	_, err := Chain{}.Search(context.Background(), "hello")
+	assert(t, err != nil, "empty chain should fail")
+	assert(t, Chain{}.Prefix() == "", "empty chain has no prefix")
+	assert(t, Merged{}.Prefix() == "", "empty merged has no prefix")
+}
+
+func TestMerged_fails_only_when_all_fail(t *testing.T) {
+	failing := &fake{name: "a", err: errors.New("down")}
+	ok := &fake{name: "b", versions: []*lib.Version{{Name: "hello", Version: "1.0"}}}
+	versions, err := Merged{failing, ok}.Search(context.Background(), "hello")
+	assertNoErr(t, err)
+	assert(t, len(versions) == 1, "versions from the working backend")
+
+	_, err = Merged{failing, &fake{name: "c", err: errors.New("gone")}}.Search(context.Background(), "hello")
+	assert(t, err != nil, "all failing")
+}
+
+func TestNewChain_rejects_empty_names(t *testing.T) {
+	for _, names := range []string{"", ",", "nixhub,"} {
+		_, err := NewChain(names)
+		assert(t, err != nil, "should reject `"+names+"`")
+	}
+}
+
+func TestSpec_creates_the_same_backends(t *testing.T) {
+	for _, spec := range []string{"nixhub", "lazamar:nixos-24.05,history", "nixhub+history,git:/src/nixpkgs", "flake:github:owner/repo#pkg"} {
+		b, err := NewChain(spec)
+		assertNoErr(t, err)
+		assert(t, Spec(b) == spec, "spec of `"+spec+"` was `"+Spec(b)+"`")
+	}
+}
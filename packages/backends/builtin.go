@@ -0,0 +1,192 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vic/ntv/packages/backends/lazamar"
+	"github.com/vic/ntv/packages/backends/nix_packages_com"
+	"github.com/vic/ntv/packages/backends/nixhub"
+	"github.com/vic/ntv/packages/backends/nixpkgs_git"
+	"github.com/vic/ntv/packages/nix"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+const DefaultLazamarChannel = "nixpkgs-unstable"
+
+// System resolves the package version on the nixpkgs flake of the current system registry.
+type System struct{}
+
+// Flake resolves the version of a flake installable. Not selected by prefix,
+// package-specs that look like installables default to it.
+type Flake struct {
+	Installable string
+}
+
+type NixHub struct{}
+
+type Lazamar struct {
+	Channel string
+}
+
+// History is the backend for https://history.nix-packages.com/
+type History struct{}
+
+// Git reads versions from the history of a local nixpkgs clone.
+type Git struct {
+	Repo string
+}
+
+func init() {
+	Register(&Registration{
+		Name:        "system",
+		Description: "Use the nixpkgs flake from the system registry.",
+		Prefix:      "system:",
+		New:         func(string) (VersionsBackend, error) { return &System{}, nil },
+	})
+	Register(&Registration{
+		Name:        "nixhub",
+		Short:       'n',
+		Description: "Use https://nixhub.io for version search.",
+		Prefix:      "nixhub:",
+		New:         func(string) (VersionsBackend, error) { return &NixHub{}, nil },
+	})
+	Register(&Registration{
+		Name:        "history",
+		Description: "Use https://history.nix-packages.com for version search.",
+		Prefix:      "history:",
+		New:         func(string) (VersionsBackend, error) { return &History{}, nil },
+	})
+	Register(&Registration{
+		Name:        "lazamar",
+		Short:       'l',
+		Description: "Use https://lazamar.co.uk/nix-versions/ for version search.",
+		Prefix:      "lazamar:",
+		New: func(channel string) (VersionsBackend, error) {
+			if channel == "" {
+				channel = DefaultLazamarChannel
+			}
+			return &Lazamar{Channel: channel}, nil
+		},
+		// lazamar:channel:package or lazamar:package
+		Parse: func(query string, defaultBackend VersionsBackend) (VersionsBackend, string, error) {
+			var channel = DefaultLazamarChannel
+			for _, b := range Each(defaultBackend) {
+				if l, ok := b.(*Lazamar); ok {
+					channel = l.Channel
+					break
+				}
+			}
+			if strings.Contains(query, ":") {
+				parts := strings.SplitN(query, ":", 2)
+				channel = parts[0]
+				query = parts[1]
+			}
+			return &Lazamar{Channel: channel}, query, nil
+		},
+	})
+	Register(&Registration{
+		Name:        "git",
+		Description: "Use the history of a local nixpkgs clone. See --nixpkgs-repo.",
+		Prefix:      "git:",
+		New: func(repo string) (VersionsBackend, error) {
+			if repo == "" {
+				repo = os.Getenv("NTV_NIXPKGS_REPO")
+			}
+			if repo == "" {
+				return nil, fmt.Errorf("the git backend needs a nixpkgs clone: use `--nixpkgs-repo PATH` or `$NTV_NIXPKGS_REPO`")
+			}
+			return &Git{Repo: repo}, nil
+		},
+		Parse: func(query string, defaultBackend VersionsBackend) (VersionsBackend, string, error) {
+			for _, b := range Each(defaultBackend) {
+				if g, ok := b.(*Git); ok {
+					return g, query, nil
+				}
+			}
+			b, err := Lookup("git").New("")
+			return b, query, err
+		},
+	})
+	Register(&Registration{
+		Name: "flake",
+		New: func(installable string) (VersionsBackend, error) {
+			if installable == "" {
+				return nil, fmt.Errorf("the flake backend expects an installable: `flake:github:owner/repo#package`")
+			}
+			return &Flake{Installable: installable}, nil
+		},
+	})
+}
+
+func (*System) Name() string   { return "system" }
+func (*System) Prefix() string { return "system:" }
+func (*System) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	pv, err := nix.InstallablePackageVersion(ctx, "nixpkgs#"+attr)
+	if err != nil {
+		return nil, err
+	}
+	one := lib.Version{
+		Name:      pv.PackageName,
+		Version:   pv.Version,
+		Attribute: attr,
+		Flake:     "nixpkgs",
+		Revision:  "",
+	}
+	return []*lib.Version{&one}, nil
+}
+
+func (*Flake) Name() string   { return "flake" }
+func (*Flake) Prefix() string { return "" }
+func (b *Flake) Search(ctx context.Context, _ string) ([]*lib.Version, error) {
+	pv, err := nix.InstallablePackageVersion(ctx, b.Installable)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		attribute = "default"
+		flake     = b.Installable
+	)
+	if strings.Contains(b.Installable, "#") {
+		idx := strings.LastIndex(b.Installable, "#")
+		flake = b.Installable[:idx]
+		attribute = b.Installable[idx+1:]
+	}
+	one := lib.Version{
+		Name:      pv.PackageName,
+		Version:   pv.Version,
+		Attribute: attribute,
+		Flake:     flake,
+		Revision:  "",
+	}
+	return []*lib.Version{&one}, nil
+}
+
+func (*NixHub) Name() string   { return "nixhub" }
+func (*NixHub) Prefix() string { return "nixhub:" }
+func (*NixHub) Host() string   { return "search.devbox.sh" }
+func (*NixHub) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	return nixhub.Search(ctx, attr)
+}
+
+func (b *Lazamar) Name() string { return "lazamar:" + b.Channel }
+func (*Lazamar) Prefix() string { return "lazamar:" }
+func (*Lazamar) Host() string   { return "lazamar.co.uk" }
+func (b *Lazamar) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	return lazamar.Search(ctx, attr, b.Channel)
+}
+
+func (*History) Name() string   { return "history" }
+func (*History) Prefix() string { return "history:" }
+func (*History) Host() string   { return "api.history.nix-packages.com" }
+func (*History) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	return nix_packages_com.Search(ctx, attr)
+}
+
+func (*Git) Name() string   { return "git" }
+func (*Git) Prefix() string { return "git:" }
+func (b *Git) Search(ctx context.Context, attr string) ([]*lib.Version, error) {
+	return nixpkgs_git.Search(ctx, b.Repo, attr)
+}
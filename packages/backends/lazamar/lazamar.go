@@ -8,10 +8,11 @@ import (
 
 	"github.com/antchfx/htmlquery"
 	"github.com/carlmjohnson/requests"
+	"github.com/vic/ntv/packages/throttle"
 	lib "github.com/vic/ntv/packages/versions"
 )
 
-func Search(name string, channel string) ([]*lib.Version, error) {
+func Search(ctx context.Context, name string, channel string) ([]*lib.Version, error) {
 	var (
 		body   string
 		result []*lib.Version
@@ -21,7 +22,8 @@ func Search(name string, channel string) ([]*lib.Version, error) {
 		Param("channel", channel).
 		Param("package", name).
 		ToString(&body).
-		Fetch(context.Background())
+		Client(throttle.Client).
+		Fetch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching versions from lazamar.co.uk for `%s`: %v\nPerhaps the package is not available on nixpkgs under the `%s` name.\nTry using `*%s*` as argument or use https://search.nixos.org/packages?query=%s to find the proper attribute name", name, err, name, name, name)
 	}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/carlmjohnson/requests"
+	"github.com/vic/ntv/packages/throttle"
 	lib "github.com/vic/ntv/packages/versions"
 )
 
@@ -16,7 +17,7 @@ type version struct {
 	Revision string `json:"revision"`
 }
 
-func Search(name string) ([]*lib.Version, error) {
+func Search(ctx context.Context, name string) ([]*lib.Version, error) {
 	var (
 		body   []version
 		result []*lib.Version
@@ -26,7 +27,8 @@ func Search(name string) ([]*lib.Version, error) {
 		Method("GET").
 		Accept("application/json").
 		ToJSON(&body).
-		Fetch(context.Background())
+		Client(throttle.Client).
+		Fetch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching versions from history.nix-packages.com for `%s`: %v\nPerhaps the package is not available on history.nix-packages.com under the `%s` name.\nTry using `*%s*` as argument or use https://history.nix-packages.com/search?search=%s to find the proper attribute name", name, err, name, name, name)
 	}
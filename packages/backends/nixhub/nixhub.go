@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/carlmjohnson/requests"
+	"github.com/vic/ntv/packages/throttle"
 	lib "github.com/vic/ntv/packages/versions"
 )
 
@@ -23,7 +24,7 @@ type response struct {
 	Releases []release `json:"releases"`
 }
 
-func Search(name string) ([]*lib.Version, error) {
+func Search(ctx context.Context, name string) ([]*lib.Version, error) {
 	var (
 		body   response
 		result []*lib.Version
@@ -34,7 +35,8 @@ func Search(name string) ([]*lib.Version, error) {
 		Param("name", name).
 		Accept("application/json").
 		ToJSON(&body).
-		Fetch(context.Background())
+		Client(throttle.Client).
+		Fetch(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching versions from nixhub.io for `%s`: %v\nPerhaps the package is not available on nixhub.io under the `%s` name.\nTry using `*%s*` as argument or use https://www.nixhub.io/search?q=%s to find the proper attribute name", name, err, name, name, name)
 	}
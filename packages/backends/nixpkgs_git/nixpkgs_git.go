@@ -0,0 +1,172 @@
+package nixpkgs_git
+
+// Backend reading versions from the history of a local nixpkgs git clone.
+//
+// The file defining an attribute is located at HEAD, either on
+// `pkgs/by-name` or from its `callPackage` on `all-packages.nix`.
+// Then every commit touching that file is walked (following renames)
+// and the `version = "..."` it declares is read at each commit.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vic/ntv/packages/throttle"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+var (
+	versionRegex = regexp.MustCompile(`(?m)^\s*version\s*=\s*"([^"$]+)"\s*;`)
+	hashRegex    = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+func Search(ctx context.Context, repo string, attr string) ([]*lib.Version, error) {
+	file, err := locate(ctx, repo, attr)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := history(ctx, repo, file)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := catFiles(ctx, repo, commits)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result []*lib.Version
+		seen   = map[string]bool{}
+	)
+	// newest commits first, so each version gets the latest revision having it.
+	for i, c := range commits {
+		m := versionRegex.FindSubmatch(contents[i])
+		if m == nil || seen[string(m[1])] {
+			continue
+		}
+		seen[string(m[1])] = true
+		version := lib.Version{
+			Name:      attr,
+			Attribute: attr,
+			Version:   string(m[1]),
+			Revision:  c.hash,
+			Flake:     "nixpkgs",
+		}
+		result = append(result, &version)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no versions found for `%s` on the history of `%s` at %s", attr, file, repo)
+	}
+	return result, nil
+}
+
+type commit struct {
+	hash string
+	file string
+}
+
+func byNamePath(attr string) string {
+	shard := strings.ToLower(attr)
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return path.Join("pkgs/by-name", shard, attr, "package.nix")
+}
+
+// the file defining attr at HEAD.
+func locate(ctx context.Context, repo string, attr string) (string, error) {
+	byName := byNamePath(attr)
+	if _, err := git(ctx, repo, nil, "cat-file", "-e", "HEAD:"+byName); err == nil {
+		return byName, nil
+	}
+
+	allPackages := "pkgs/top-level/all-packages.nix"
+	content, err := git(ctx, repo, nil, "show", "HEAD:"+allPackages)
+	if err != nil {
+		return "", fmt.Errorf("%s does not look like a nixpkgs repository: %v", repo, err)
+	}
+	callPackage := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(attr) + `\s*=\s*callPackage\s+(\.\./\S+)`)
+	m := callPackage.FindSubmatch(content)
+	if m == nil {
+		return "", fmt.Errorf("could not find the file defining `%s` on %s. only `pkgs/by-name` and `callPackage` attributes are supported", attr, repo)
+	}
+	file := path.Join(path.Dir(allPackages), string(m[1]))
+	if !strings.HasSuffix(file, ".nix") {
+		file = path.Join(file, "default.nix")
+	}
+	return file, nil
+}
+
+// commits touching file, newest first, with the file name at each one.
+func history(ctx context.Context, repo string, file string) ([]commit, error) {
+	out, err := git(ctx, repo, nil, "log", "--follow", "--name-only", "--format=%H", "HEAD", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		commits []commit
+		hash    string
+	)
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if hashRegex.MatchString(line) {
+			hash = line
+		} else if line != "" && hash != "" {
+			commits = append(commits, commit{hash: hash, file: line})
+			hash = ""
+		}
+	}
+	return commits, nil
+}
+
+// contents of each commit file, read with a single `git cat-file --batch`.
+func catFiles(ctx context.Context, repo string, commits []commit) ([][]byte, error) {
+	in := bytes.Buffer{}
+	for _, c := range commits {
+		fmt.Fprintf(&in, "%s:%s\n", c.hash, c.file)
+	}
+	out, err := git(ctx, repo, &in, "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(bytes.NewReader(out))
+	contents := make([][]byte, len(commits))
+	for i := range commits {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			continue // missing
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		contents[i] = make([]byte, size+1) // content and its trailing newline
+		if _, err := io.ReadFull(reader, contents[i]); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+func git(ctx context.Context, repo string, stdin io.Reader, args ...string) ([]byte, error) {
+	release, err := throttle.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	cmd.Stdin = stdin
+	return cmd.Output()
+}
@@ -0,0 +1,95 @@
+package nixpkgs_git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// a tiny nixpkgs-like repository with a few package versions.
+func fixtureRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=ntv", "GIT_AUTHOR_EMAIL=ntv@example.com",
+			"GIT_COMMITTER_NAME=ntv", "GIT_COMMITTER_EMAIL=ntv@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(file, content string) {
+		file = filepath.Join(repo, file)
+		assertNoErr(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		assertNoErr(t, os.WriteFile(file, []byte(content), 0o644))
+	}
+	commit := func(msg string) {
+		run("add", "-A")
+		run("commit", "-q", "-m", msg)
+	}
+	pkg := func(version string) string {
+		return "{ stdenv }:\nstdenv.mkDerivation rec {\n  pname = \"hello\";\n  version = \"" + version + "\";\n}\n"
+	}
+
+	run("init", "-q")
+	write("pkgs/top-level/all-packages.nix", "{\n  hello = callPackage ../applications/misc/hello { };\n  cowsay = callPackage ../tools/cowsay.nix { };\n}\n")
+	write("pkgs/applications/misc/hello/default.nix", pkg("2.10"))
+	write("pkgs/tools/cowsay.nix", "{\n  version = \"3.7.0\";\n}\n")
+	commit("init")
+	write("pkgs/applications/misc/hello/default.nix", pkg("2.12"))
+	commit("hello: 2.10 -> 2.12")
+	write("pkgs/applications/misc/hello/default.nix", pkg("2.12")+"# meta fix\n")
+	commit("hello: fix meta")
+	// moved to by-name
+	assertNoErr(t, os.MkdirAll(filepath.Join(repo, "pkgs/by-name/he/hello"), 0o755))
+	run("mv", "pkgs/applications/misc/hello/default.nix", "pkgs/by-name/he/hello/package.nix")
+	write("pkgs/top-level/all-packages.nix", "{\n  cowsay = callPackage ../tools/cowsay.nix { };\n}\n")
+	commit("hello: move to by-name")
+	write("pkgs/by-name/he/hello/package.nix", pkg("2.12.1"))
+	commit("hello: 2.12 -> 2.12.1")
+	return repo
+}
+
+func TestSearch_follows_history(t *testing.T) {
+	repo := fixtureRepo(t)
+	versions, err := Search(context.Background(), repo, "hello")
+	assertNoErr(t, err)
+	found := []string{}
+	for _, v := range versions {
+		found = append(found, v.Version)
+		assert(t, len(v.Revision) == 40, "has a revision")
+		assert(t, v.Attribute == "hello", "attribute")
+	}
+	assert(t, len(found) == 3, "three versions")
+	assert(t, found[0] == "2.12.1" && found[1] == "2.12" && found[2] == "2.10", "newest first")
+}
+
+func TestSearch_callPackage_file(t *testing.T) {
+	repo := fixtureRepo(t)
+	versions, err := Search(context.Background(), repo, "cowsay")
+	assertNoErr(t, err)
+	assert(t, len(versions) == 1 && versions[0].Version == "3.7.0", "cowsay version")
+}
+
+func TestSearch_unknown_attr(t *testing.T) {
+	repo := fixtureRepo(t)
+	_, err := Search(context.Background(), repo, "nope")
+	assert(t, err != nil, "should fail for unknown attributes")
+}
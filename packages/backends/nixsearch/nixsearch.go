@@ -4,23 +4,34 @@ import (
 	"context"
 
 	lib "github.com/peterldowns/nix-search-cli/pkg/nixsearch"
+	"github.com/vic/ntv/packages/throttle"
 )
 
 // nixos-search elastic indexes.
 // https://github.com/NixOS/nixos-search/blob/main/flake-info/src/elastic.rs
 type Package = lib.Package
 
-func FindPackagesWithAttr(maxRes int, search string) ([]lib.Package, error) {
+// retries and rate limits are done by throttle.Client
+func newClient() (*lib.ElasticSearchClient, error) {
+	client, err := lib.NewElasticSearchClient()
+	if err != nil {
+		return nil, err
+	}
+	client.HTTPClient = throttle.Client
+	return client, nil
+}
+
+func FindPackagesWithAttr(ctx context.Context, channel string, maxRes int, search string) ([]lib.Package, error) {
 	query := lib.Query{
 		MaxResults:  maxRes,
-		Channel:     "unstable",
+		Channel:     channel,
 		QueryString: &lib.MatchQueryString{QueryString: "package_attr_name: " + search},
 	}
-	client, err := lib.NewElasticSearchClient()
+	client, err := newClient()
 	if err != nil {
 		return nil, err
 	}
-	pkgs, err := client.Search(context.Background(), query)
+	pkgs, err := client.Search(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -28,17 +39,37 @@ func FindPackagesWithAttr(maxRes int, search string) ([]lib.Package, error) {
 	return pkgs, nil
 }
 
-func FindPackagesWithProgram(maxRes int, program string) ([]lib.Package, error) {
+func FindPackagesWithProgram(ctx context.Context, channel string, maxRes int, program string) ([]lib.Package, error) {
 	query := lib.Query{
 		MaxResults:  maxRes,
-		Channel:     "unstable",
+		Channel:     channel,
 		QueryString: &lib.MatchQueryString{QueryString: "package_programs: " + program},
 	}
-	client, err := lib.NewElasticSearchClient()
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := client.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	pkgs = lib.Deduplicate(pkgs)
+	return pkgs, nil
+}
+
+// FindPackagesWithText matches attribute names, programs and descriptions
+// the same way https://search.nixos.org does.
+func FindPackagesWithText(ctx context.Context, channel string, maxRes int, text string) ([]lib.Package, error) {
+	query := lib.Query{
+		MaxResults: maxRes,
+		Channel:    channel,
+		Search:     &lib.MatchSearch{Search: text},
+	}
+	client, err := newClient()
 	if err != nil {
 		return nil, err
 	}
-	pkgs, err := client.Search(context.Background(), query)
+	pkgs, err := client.Search(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,201 @@
+package cache
+
+// On-disk cache for responses of remote backends.
+//
+// Entries are JSON files under `$XDG_CACHE_HOME/ntv`, one per key.
+// A key looks like `backend/channel/attribute`, its age is the file mtime.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const DefaultTTL = 24 * time.Hour
+
+type Cache struct {
+	Dir string
+	TTL time.Duration
+	// When false, cached entries are ignored. eg. on `--refresh`.
+	Read bool
+	// When false, nothing is stored. eg. on `--no-cache`.
+	Write bool
+	// When true, only cached entries are used regardless of their age. eg. on `--offline`.
+	Offline bool
+}
+
+// ErrNotCached is returned by Fetch on Offline mode for keys without a cached entry.
+var ErrNotCached = errors.New("no cached response for")
+
+var Default = New()
+
+func New() *Cache {
+	ttl := DefaultTTL
+	if env := os.Getenv("NTV_CACHE_TTL"); env != "" {
+		if d, err := time.ParseDuration(env); err == nil {
+			ttl = d
+		}
+	}
+	return &Cache{
+		Dir:   Dir(),
+		TTL:   ttl,
+		Read:  true,
+		Write: true,
+	}
+}
+
+// Dir is `$XDG_CACHE_HOME/ntv`, falling back to the user cache directory.
+func Dir() string {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		var err error
+		if base, err = os.UserCacheDir(); err != nil {
+			base = os.TempDir()
+		}
+	}
+	return filepath.Join(base, "ntv")
+}
+
+// Key joins parts into a cache key. Each part is escaped so that
+// attributes like `*python*` or `bin/rg` are safe as file names.
+func Key(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		escaped[i] = url.PathEscape(p)
+	}
+	return strings.Join(escaped, "/")
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, filepath.FromSlash(key)+".json")
+}
+
+// Load decodes the entry for key into v. Returns false when missing or expired.
+func (c *Cache) Load(key string, v any) bool {
+	if !c.Read && !c.Offline {
+		return false
+	}
+	file := c.path(key)
+	info, err := os.Stat(file)
+	if err != nil || (!c.Offline && c.Expired(info)) {
+		return false
+	}
+	bts, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(bts, v) == nil
+}
+
+func (c *Cache) Store(key string, v any) error {
+	if !c.Write {
+		return nil
+	}
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	file := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func (c *Cache) Expired(info fs.FileInfo) bool {
+	return c.TTL >= 0 && time.Since(info.ModTime()) > c.TTL
+}
+
+// Fetch returns the cached value for key, or calls fetch and caches its result.
+// Failing to write the cache is not an error, the fetched value is still returned.
+// On Offline mode fetch is never called.
+func Fetch[T any](c *Cache, key string, fetch func() (T, error)) (T, error) {
+	var v T
+	if c.Load(key, &v) {
+		return v, nil
+	}
+	if c.Offline {
+		return v, fmt.Errorf("%w `%s`", ErrNotCached, key)
+	}
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	_ = c.Store(key, v)
+	return v, nil
+}
+
+func (c *Cache) Clear() error {
+	err := os.RemoveAll(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type Stats struct {
+	Backend string `json:"backend"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Size    int64  `json:"size"`
+}
+
+// Stats of cached entries grouped by backend, the first element of their key.
+func (c *Cache) Stats() ([]*Stats, error) {
+	byBackend := map[string]*Stats{}
+	res := []*Stats{}
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.Dir, path)
+		if err != nil {
+			return err
+		}
+		backend, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		s, ok := byBackend[backend]
+		if !ok {
+			s = &Stats{Backend: backend}
+			byBackend[backend] = s
+			res = append(res, s)
+		}
+		s.Entries++
+		s.Size += info.Size()
+		if c.Expired(info) {
+			s.Expired++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func newTestCache(t *testing.T) *Cache {
+	return &Cache{Dir: t.TempDir(), TTL: time.Hour, Read: true, Write: true}
+}
+
+func TestFetch_stores_and_reuses(t *testing.T) {
+	c := newTestCache(t)
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	}
+	key := Key("lazamar", "nixos-24.05", "*python*")
+	_, err := Fetch(c, key, fetch)
+	assertNoErr(t, err)
+	v, err := Fetch(c, key, fetch)
+	assertNoErr(t, err)
+	assert(t, calls == 1, "should fetch once")
+	assert(t, len(v) == 2 && v[1] == "b", "should load cached value")
+}
+
+func TestFetch_refresh_ignores_cached(t *testing.T) {
+	c := newTestCache(t)
+	assertNoErr(t, c.Store(Key("nixhub", "hello"), "old"))
+	c.Read = false
+	v, err := Fetch(c, Key("nixhub", "hello"), func() (string, error) { return "new", nil })
+	assertNoErr(t, err)
+	assert(t, v == "new", "should fetch again")
+	c.Read = true
+	assert(t, c.Load(Key("nixhub", "hello"), &v) && v == "new", "should have stored refreshed value")
+}
+
+func TestLoad_expired(t *testing.T) {
+	c := newTestCache(t)
+	c.TTL = 0
+	assertNoErr(t, c.Store(Key("nixhub", "hello"), "old"))
+	var v string
+	assert(t, !c.Load(Key("nixhub", "hello"), &v), "should be expired")
+}
+
+func TestStats_and_clear(t *testing.T) {
+	c := newTestCache(t)
+	assertNoErr(t, c.Store(Key("nixhub", "hello"), "x"))
+	assertNoErr(t, c.Store(Key("lazamar", "nixos-24.05", "hello"), "x"))
+	assertNoErr(t, c.Store(Key("lazamar", "nixos-24.05", "emacs"), "x"))
+	stats, err := c.Stats()
+	assertNoErr(t, err)
+	entries := map[string]int{}
+	for _, s := range stats {
+		entries[s.Backend] = s.Entries
+	}
+	assert(t, entries["nixhub"] == 1 && entries["lazamar"] == 2, "entries per backend")
+
+	assertNoErr(t, c.Clear())
+	stats, err = c.Stats()
+	assertNoErr(t, err)
+	assert(t, len(stats) == 0, "should be empty after clear")
+}
+
+func TestFetch_offline(t *testing.T) {
+	c := newTestCache(t)
+	c.TTL = 0
+	assertNoErr(t, c.Store(Key("nixhub", "hello"), "old"))
+	c.Offline = true
+	fetch := func() (string, error) {
+		t.Error("should not fetch when offline")
+		return "", nil
+	}
+	v, err := Fetch(c, Key("nixhub", "hello"), fetch)
+	assertNoErr(t, err)
+	assert(t, v == "old", "should use expired entries when offline")
+	_, err = Fetch(c, Key("nixhub", "emacs"), fetch)
+	assert(t, errors.Is(err, ErrNotCached), "should fail for missing entries")
+}
@@ -2,10 +2,13 @@ package flake
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/vic/ntv/packages/backends"
 	"github.com/vic/ntv/packages/nix"
 	"github.com/vic/ntv/packages/search"
 )
@@ -34,6 +37,10 @@ type Tool struct {
 	Name        string `json:"name"`
 	Version     string `json:"version"`
 	Installable string `json:"installable"`
+	Backend     string `json:"backend"`
+	// The backends Spec was searched with, including fallbacks. See backends.Spec
+	BackendSpec string `json:"backendSpec"`
+	Input       string `json:"input"`
 }
 
 type Context struct {
@@ -93,6 +100,18 @@ func (f *Flake) AddInput(name, url string, flake bool, follows []Follow) {
 	})
 }
 
+// RemoveInput also removes follows pointing at it.
+func (f *Flake) RemoveInput(name string) {
+	f.Inputs = slices.DeleteFunc(f.Inputs, func(in Input) bool {
+		return in.Name == name
+	})
+	for i, in := range f.Inputs {
+		f.Inputs[i].Follows = slices.DeleteFunc(in.Follows, func(follow Follow) bool {
+			return follow.Follow == name
+		})
+	}
+}
+
 func (f *Flake) AddFollow(name, input, follow string) {
 	for i, in := range f.Inputs {
 		if in.Name == name {
@@ -105,25 +124,101 @@ func (f *Flake) AddFollow(name, input, follow string) {
 }
 
 func (f *Flake) AddImport(importPath string) {
+	if slices.Contains(f.Imports, importPath) {
+		return
+	}
 	f.Imports = append(f.Imports, importPath)
 }
 
+// OciImageModule adds a `packages.<system>.image` OCI image having all tools.
+const OciImageModule = "inputs.ntv.flakeModules.oci-image"
+
+// InputName of the flake input providing the selected version.
+// Tools pinned to the same nixpkgs revision share a `nixpkgs-<shortrev>` input.
+func InputName(r *search.PackageSearchResult) string {
+	v := r.Selected
+	url := r.FlakeUrl(v)
+	if url == "nixpkgs" {
+		return "nixpkgs"
+	}
+	if v.Flake == "nixpkgs" && v.Revision != "" {
+		return "nixpkgs-" + search.ShortRevision(v.Revision)
+	}
+	return v.Name
+}
+
 func AsTool(r *search.PackageSearchResult) Tool {
 	return Tool{
 		Spec:        *r.FromSearch.Spec,
 		Name:        r.Selected.Name,
 		Version:     r.Selected.Version,
 		Installable: r.Installable(r.Selected),
+		Backend:     r.Backend.Name(),
+		BackendSpec: backendSpec(r),
+		Input:       InputName(r),
+	}
+}
+
+func backendSpec(r *search.PackageSearchResult) string {
+	if r.FromSearch.VersionsBackend == nil {
+		return backends.Spec(r.Backend)
 	}
+	return backends.Spec(r.FromSearch.VersionsBackend)
 }
 
+// AddTool reuses an existing input having the same flake url, if any.
+// A tool with the same name is replaced, dropping its input when no longer used.
 func (c *Context) AddTool(r *search.PackageSearchResult) {
+	tool := AsTool(r)
+	tool.Input = c.Flake.sharedInput(tool.Input, r.FlakeUrl(r.Selected))
+	old, replaced := c.Tools[tool.Name]
+	c.Tools[tool.Name] = tool
+	if replaced && !c.InputUsed(old.Input) {
+		c.Flake.RemoveInput(old.Input)
+	}
+}
 
-	c.Tools[r.Selected.Name] = AsTool(r)
+// RemoveTools deletes the named tools.
+// Returns the inputs they used that no other tool uses anymore.
+func (c *Context) RemoveTools(names ...string) ([]string, error) {
+	unused := []string{}
+	for _, name := range names {
+		tool, ok := c.Tools[name]
+		if !ok {
+			return nil, fmt.Errorf("no tool named `%s` on flake", name)
+		}
+		delete(c.Tools, name)
+		if !c.InputUsed(tool.Input) && !slices.Contains(unused, tool.Input) && !isCoreInput(tool.Input) {
+			unused = append(unused, tool.Input)
+		}
+	}
+	return slices.DeleteFunc(unused, c.InputUsed), nil
+}
 
-	if r.FromSearch.VersionsBackend.CurrentNixpkgs == nil {
-		c.Flake.AddInput(r.Selected.Name, r.FlakeUrl(r.Selected), true, []Follow{})
+// inputs every generated flake has.
+func isCoreInput(name string) bool {
+	return name == "nixpkgs" || name == "ntv"
+}
+
+// InputUsed tells if some tool uses the named input.
+func (c *Context) InputUsed(name string) bool {
+	for _, t := range c.Tools {
+		if t.Input == name {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the name of the input having url, adding it as name when missing.
+func (f *Flake) sharedInput(name, url string) string {
+	for _, in := range f.Inputs {
+		if in.Url == url {
+			return in.Name
+		}
 	}
+	f.AddInput(name, url, true, []Follow{})
+	return name
 }
 
 func unpackArray[S ~[]E, E any](s S) []any {
@@ -134,14 +229,14 @@ func unpackArray[S ~[]E, E any](s S) []any {
 	return r
 }
 
-func (c *Context) Render(canRunNix bool) (string, error) {
+func (c *Context) Render(ctx context.Context, canRunNix bool) (string, error) {
 	jsonBytes, err := json.Marshal(c)
 	if err != nil {
 		return "", err
 	}
 	var nixCode = "(builtins.fromJSON ''" + string(jsonBytes) + "'')"
 	if canRunNix {
-		nixCode, err = nix.JsonToNix(string(jsonBytes))
+		nixCode, err = nix.JsonToNix(ctx, string(jsonBytes))
 		if err != nil {
 			return "", err
 		}
@@ -186,7 +281,7 @@ func (c *Context) Render(canRunNix bool) (string, error) {
 
 	var code = buff.String()
 	if canRunNix {
-		code, err = nix.NixfmtCode(code)
+		code, err = nix.NixfmtCode(ctx, code)
 		if err != nil {
 			return "", err
 		}
@@ -0,0 +1,137 @@
+package flake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func result(backend backends.VersionsBackend, v *lib.Version) *search.PackageSearchResult {
+	spec := v.Name
+	return &search.PackageSearchResult{
+		FromSearch: &search.PackageSearchSpec{Spec: &spec},
+		Backend:    backend,
+		Versions:   []*lib.Version{v},
+		Selected:   v,
+	}
+}
+
+func TestAddTool_shares_inputs_by_revision(t *testing.T) {
+	rev := "0123456789abcdef0123456789abcdef01234567"
+	c := New()
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "hello", Version: "1", Attribute: "hello", Flake: "nixpkgs", Revision: rev}))
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "cowsay", Version: "2", Attribute: "cowsay", Flake: "nixpkgs", Revision: rev}))
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "jq", Version: "3", Attribute: "jq", Flake: "nixpkgs", Revision: "fedcba9876543210fedcba9876543210fedcba98"}))
+
+	assert(t, len(c.Flake.Inputs) == 4, "nixpkgs, ntv and two revisions")
+	assert(t, c.Tools["hello"].Input == "nixpkgs-0123456", "hello input")
+	assert(t, c.Tools["cowsay"].Input == "nixpkgs-0123456", "cowsay shares hello input")
+	assert(t, c.Tools["jq"].Input == "nixpkgs-fedcba9", "jq input")
+}
+
+func TestAddTool_system_uses_nixpkgs_input(t *testing.T) {
+	c := New()
+	c.AddTool(result(&backends.System{}, &lib.Version{Name: "hello", Version: "1", Attribute: "hello", Flake: "nixpkgs"}))
+	assert(t, len(c.Flake.Inputs) == 2, "no new inputs")
+	assert(t, c.Tools["hello"].Input == "nixpkgs", "system nixpkgs input")
+}
+
+func TestAddTool_replacing_drops_unused_input(t *testing.T) {
+	c := New()
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "hello", Version: "1", Attribute: "hello", Flake: "nixpkgs", Revision: "aaaaaaa"}))
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "hello", Version: "2", Attribute: "hello", Flake: "nixpkgs", Revision: "bbbbbbb"}))
+	assert(t, len(c.Flake.Inputs) == 3, "old input removed")
+	assert(t, c.Flake.Inputs[2].Name == "nixpkgs-bbbbbbb", "new input")
+}
+
+func TestLoad_embedded_json(t *testing.T) {
+	c := New()
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "hello", Version: "1", Attribute: "hello", Flake: "nixpkgs", Revision: "aaaaaaa"}))
+	file := filepath.Join(t.TempDir(), "flake.nix")
+	if err := c.Write(context.Background(), file, false); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, loaded.Tools["hello"] == c.Tools["hello"], "same tools")
+	assert(t, len(loaded.Flake.Inputs) == len(c.Flake.Inputs), "same inputs")
+	assert(t, loaded.Flake.MkFlake == c.Flake.MkFlake, "same flake")
+}
+
+func TestRemoveTools_returns_unused_inputs(t *testing.T) {
+	c := New()
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "hello", Version: "1", Attribute: "hello", Flake: "nixpkgs", Revision: "aaaaaaa"}))
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "cowsay", Version: "1", Attribute: "cowsay", Flake: "nixpkgs", Revision: "aaaaaaa"}))
+	c.AddTool(result(&backends.NixHub{}, &lib.Version{Name: "jq", Version: "1", Attribute: "jq", Flake: "nixpkgs", Revision: "bbbbbbb"}))
+	c.Flake.AddFollow("ntv", "other", "nixpkgs-bbbbbbb")
+
+	unused, err := c.RemoveTools("hello", "jq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(unused) == 1 && unused[0] == "nixpkgs-bbbbbbb", "only jq input unused")
+
+	c.Flake.RemoveInput("nixpkgs-bbbbbbb")
+	assert(t, len(c.Flake.Inputs[1].Follows) == 1, "follows pointing at removed input dropped")
+
+	_, err = c.RemoveTools("nope")
+	assert(t, err != nil, "unknown tool")
+}
+
+func TestReferencedInputs(t *testing.T) {
+	dir := t.TempDir()
+	module := "{ inputs, ... }: { perSystem = { inputs', ... }: { packages.x = inputs'.\"nixpkgs-aaaaaaa\".legacyPackages.hello; }; }"
+	if err := os.WriteFile(filepath.Join(dir, "flakeModule.nix"), []byte(module), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Flake.AddImport("inputs.nixpkgs-ccccccc.nixosModules.foo")
+	refs, err := c.ReferencedInputs(filepath.Join(dir, "flake.nix"), []string{"nixpkgs-aaaaaaa", "nixpkgs-bbbbbbb", "nixpkgs-ccccccc", "nixpkgs-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, len(refs) == 2 && refs[0] == "nixpkgs-aaaaaaa" && refs[1] == "nixpkgs-ccccccc", "referenced inputs")
+}
+
+func TestPickTool_by_selected_name(t *testing.T) {
+	py := result(&backends.NixHub{}, &lib.Version{Name: "python3", Version: "3.12"})
+	pyMin := result(&backends.NixHub{}, &lib.Version{Name: "python3Minimal", Version: "3.12"})
+	assert(t, pickTool("python3Minimal", search.PackageSearchResults{py, pyMin}) == pyMin, "picks by name")
+	assert(t, pickTool("python", search.PackageSearchResults{py, pyMin}) == nil, "ambiguous")
+	assert(t, pickTool("python", search.PackageSearchResults{py}) == py, "single result")
+}
+
+func TestAddImport_once(t *testing.T) {
+	c := New()
+	c.Flake.AddImport(OciImageModule)
+	c.Flake.AddImport(OciImageModule)
+	assert(t, len(c.Flake.Imports) == 1, "import added once")
+}
+
+func TestToolBackend_uses_the_original_chain(t *testing.T) {
+	b, err := toolBackend("hello", Tool{Backend: "history", BackendSpec: "nixhub,history"}, &backends.System{})
+	assert(t, err == nil, "known chain")
+	assert(t, backends.Spec(b) == "nixhub,history", "whole chain, not only the resolving backend")
+
+	b, err = toolBackend("hello", Tool{Backend: "lazamar:nixos-24.05"}, &backends.System{})
+	assert(t, err == nil && backends.Spec(b) == "lazamar:nixos-24.05", "resolving backend of older flakes")
+
+	b, err = toolBackend("hello", Tool{}, &backends.System{})
+	assert(t, err == nil && b.Name() == "system", "unknown backend uses the default")
+
+	_, err = toolBackend("hello", Tool{BackendSpec: "nixhub+gone"}, &backends.System{})
+	assert(t, err != nil, "should fail instead of using the default")
+}
@@ -0,0 +1,82 @@
+package flake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/vic/ntv/packages/nix"
+)
+
+var (
+	embeddedJSON = regexp.MustCompile(`(?s)\bntv = \(builtins\.fromJSON ''(.*?)''\);`)
+	embeddedNix  = regexp.MustCompile(`(?s)\bntv = (.*?);\s*systems = `)
+)
+
+// Load the ntv data of a flake.nix generated by Render.
+//
+// The data embedded on the flake is read without evaluating the flake,
+// as JSON or converted from Nix with nix-instantiate. When not found, the
+// flake `lib.ntv` output is evaluated, which might need to fetch its inputs.
+func Load(ctx context.Context, file string) (*Context, error) {
+	code, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := embeddedData(ctx, code)
+	if err != nil {
+		dir, absErr := filepath.Abs(filepath.Dir(file))
+		if absErr != nil {
+			return nil, absErr
+		}
+		var evalErr error
+		if data, evalErr = nix.NvJSON(ctx, "path:"+dir); evalErr != nil {
+			return nil, fmt.Errorf("could not load ntv data from %s: %v\n%v", file, err, evalErr)
+		}
+	}
+	c := &Context{}
+	if err := json.Unmarshal([]byte(data), c); err != nil {
+		return nil, fmt.Errorf("could not load ntv data from %s: %v", file, err)
+	}
+	if c.Tools == nil {
+		c.Tools = map[string]Tool{}
+	}
+	// flakes generated before tools had inputs, used their name.
+	for name, tool := range c.Tools {
+		if tool.Input == "" {
+			tool.Input = tool.Name
+			c.Tools[name] = tool
+		}
+	}
+	for i, in := range c.Flake.Inputs {
+		if in.Follows == nil {
+			c.Flake.Inputs[i].Follows = []Follow{}
+		}
+	}
+	if c.Flake.Imports == nil {
+		c.Flake.Imports = []string{}
+	}
+	return c, nil
+}
+
+func embeddedData(ctx context.Context, code []byte) (string, error) {
+	if m := embeddedJSON.FindSubmatch(code); m != nil {
+		return string(m[1]), nil
+	}
+	if m := embeddedNix.FindSubmatch(code); m != nil {
+		return nix.NixToJSON(ctx, string(m[1]))
+	}
+	return "", fmt.Errorf("no ntv data embedded on flake")
+}
+
+// Write renders the flake code into file.
+func (c *Context) Write(ctx context.Context, file string, canRunNix bool) error {
+	code, err := c.Render(ctx, canRunNix)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(code), 0o644)
+}
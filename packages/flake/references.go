@@ -0,0 +1,28 @@
+package flake
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ReferencedInputs returns which of inputs are referenced by user code:
+// the `flakeModule.nix` next to the flake file, or the flake imports.
+func (c *Context) ReferencedInputs(file string, inputs []string) ([]string, error) {
+	code, err := os.ReadFile(filepath.Join(filepath.Dir(file), "flakeModule.nix"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, imp := range c.Flake.Imports {
+		code = append(code, []byte("\n"+imp)...)
+	}
+	referenced := []string{}
+	for _, name := range inputs {
+		quoted := regexp.QuoteMeta(name)
+		ref := regexp.MustCompile(`inputs'?\.(?:"` + quoted + `"|` + quoted + `)(?:[^\w'-]|$)`)
+		if ref.Match(code) {
+			referenced = append(referenced, name)
+		}
+	}
+	return referenced, nil
+}
@@ -0,0 +1,122 @@
+package flake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/vic/ntv/packages/backends"
+	"github.com/vic/ntv/packages/search"
+	"github.com/vic/ntv/packages/search_spec"
+)
+
+// Resolve searches again the original spec of the named tools, or all when no names given.
+// Each tool is searched on the same backends it was first searched with, never silently on others.
+func (c *Context) Resolve(ctx context.Context, names []string, defaultBackend backends.VersionsBackend, nixSearch search_spec.NixSearch) (map[string]*search.PackageSearchResult, error) {
+	resolved, failed, err := c.ResolveEach(ctx, names, defaultBackend, nixSearch)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	for _, name := range slices.Sorted(maps.Keys(failed)) {
+		errs = append(errs, failed[name])
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// ResolveEach is like Resolve, but a tool failing to resolve does not stop the others.
+// The error of each failed tool is returned by tool name.
+func (c *Context) ResolveEach(ctx context.Context, names []string, defaultBackend backends.VersionsBackend, nixSearch search_spec.NixSearch) (map[string]*search.PackageSearchResult, map[string]error, error) {
+	if len(names) == 0 {
+		for name := range c.Tools {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		if _, ok := c.Tools[name]; !ok {
+			return nil, nil, fmt.Errorf("no tool named `%s` on flake", name)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		resolved = map[string]*search.PackageSearchResult{}
+		failed   = map[string]error{}
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := c.resolveTool(ctx, name, defaultBackend, nixSearch)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[name] = err
+				return
+			}
+			resolved[name] = r
+		}()
+	}
+	wg.Wait()
+	return resolved, failed, nil
+}
+
+func (c *Context) resolveTool(ctx context.Context, name string, defaultBackend backends.VersionsBackend, nixSearch search_spec.NixSearch) (*search.PackageSearchResult, error) {
+	tool := c.Tools[name]
+	backend, err := toolBackend(name, tool, defaultBackend)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := search_spec.ParseSearchSpecs([]string{tool.Spec}, backend)
+	if err != nil {
+		return nil, err
+	}
+	res, err := search.PackageSearchSpecs(specs.WithNixSearch(nixSearch)).Search(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := pickTool(name, res)
+	if r == nil {
+		return nil, fmt.Errorf("no versions found for tool `%s` with spec `%s`", name, tool.Spec)
+	}
+	return r, nil
+}
+
+// Tools from flakes generated before BackendSpec existed are searched on the backend that resolved them.
+// Only tools whose backend is unknown use defaultBackend.
+func toolBackend(name string, tool Tool, defaultBackend backends.VersionsBackend) (backends.VersionsBackend, error) {
+	spec := tool.BackendSpec
+	if spec == "" {
+		spec = tool.Backend
+	}
+	// the installable of flake tools is their package-spec.
+	if spec == "" || spec == (&backends.Flake{}).Name() {
+		return defaultBackend, nil
+	}
+	backend, err := backends.NewChain(spec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot search tool `%s` again on backend `%s`: %w", name, spec, err)
+	}
+	return backend, nil
+}
+
+// the result for the tool name, a spec like `*python*` can match many packages.
+func pickTool(name string, res search.PackageSearchResults) *search.PackageSearchResult {
+	for _, r := range res {
+		if r.Selected != nil && r.Selected.Name == name {
+			return r
+		}
+	}
+	if len(res) == 1 && res[0].Selected != nil {
+		return res[0]
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package nix
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vic/ntv/packages/throttle"
+)
+
+// PrefetchTarball returns the sha256 for `builtins.fetchTarball` using nix-prefetch-url.
+func PrefetchTarball(ctx context.Context, url string) (string, error) {
+	out, err := Run(ctx, "nix-prefetch-url", "--unpack", "--type", "sha256", url)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return lines[len(lines)-1], nil
+}
+
+// FetchTarballHash returns the same sha256 as PrefetchTarball without needing nix.
+// The tarball is unpacked to a temporary directory and its NAR serialization hashed.
+func FetchTarballHash(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := throttle.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	dir, err := os.MkdirTemp("", "ntv-tarball-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	root, err := UnpackTarball(resp.Body, dir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := NarHash(root)
+	if err != nil {
+		return "", err
+	}
+	return Base32(hash), nil
+}
+
+// UnpackTarball extracts a gzipped tarball into dir.
+// Like fetchTarball, it must have a single top-level directory, which is returned.
+func UnpackTarball(r io.Reader, dir string) (string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := checkEntry(dir, hdr); err != nil {
+			return "", err
+		}
+		path := filepath.Join(dir, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0o755)
+		case tar.TypeReg:
+			err = writeFile(path, tr, hdr.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = os.Symlink(hdr.Linkname, path)
+		case tar.TypeLink:
+			err = os.Link(filepath.Join(dir, hdr.Linkname), path)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("tarball must have a single top-level directory")
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
+
+// entries cannot be written outside of dir: neither by their name, hard link target,
+// nor through a symlink extracted earlier.
+func checkEntry(dir string, hdr *tar.Header) error {
+	if !filepath.IsLocal(hdr.Name) {
+		return fmt.Errorf("tarball entry outside of its root: %s", hdr.Name)
+	}
+	names := []string{hdr.Name}
+	if hdr.Typeflag == tar.TypeLink {
+		if !filepath.IsLocal(hdr.Linkname) {
+			return fmt.Errorf("tarball hard link outside of its root: %s -> %s", hdr.Name, hdr.Linkname)
+		}
+		names = append(names, hdr.Linkname)
+	}
+	for _, name := range names {
+		linked, err := throughSymlink(dir, name)
+		if err != nil {
+			return err
+		}
+		if linked {
+			return fmt.Errorf("tarball entry through a symlink: %s", name)
+		}
+	}
+	if info, err := os.Lstat(filepath.Join(dir, hdr.Name)); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("tarball entry replaces a symlink: %s", hdr.Name)
+	}
+	return nil
+}
+
+// tells if a parent directory of name, relative to dir, is a symlink.
+func throughSymlink(dir, name string) (bool, error) {
+	path := dir
+	for _, part := range strings.Split(filepath.Dir(filepath.Clean(name)), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NarHash is the sha256 of the Nix ARchive serialization of path.
+func NarHash(path string) ([]byte, error) {
+	h := sha256.New()
+	if err := WriteNar(h, path); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// WriteNar serializes path as a Nix ARchive.
+// Only the executable bit of files is kept, directory entries are sorted by name.
+func WriteNar(w io.Writer, path string) error {
+	nw := &narWriter{w: w}
+	nw.str("nix-archive-1")
+	nw.node(path)
+	return nw.err
+}
+
+type narWriter struct {
+	w   io.Writer
+	err error
+}
+
+// strings are length prefixed and padded to 8 bytes.
+func (nw *narWriter) str(s string) {
+	if nw.err != nil {
+		return
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(s)))
+	if _, nw.err = nw.w.Write(buf[:]); nw.err != nil {
+		return
+	}
+	if _, nw.err = io.WriteString(nw.w, s); nw.err != nil {
+		return
+	}
+	if pad := (8 - len(s)%8) % 8; pad > 0 {
+		_, nw.err = nw.w.Write(make([]byte, pad))
+	}
+}
+
+func (nw *narWriter) node(path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		nw.err = err
+		return
+	}
+	nw.str("(")
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			nw.err = err
+			return
+		}
+		nw.str("type")
+		nw.str("symlink")
+		nw.str("target")
+		nw.str(target)
+	case info.IsDir():
+		nw.str("type")
+		nw.str("directory")
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			nw.err = err
+			return
+		}
+		for _, e := range entries {
+			nw.str("entry")
+			nw.str("(")
+			nw.str("name")
+			nw.str(e.Name())
+			nw.str("node")
+			nw.node(filepath.Join(path, e.Name()))
+			nw.str(")")
+		}
+	default:
+		nw.str("type")
+		nw.str("regular")
+		if info.Mode()&0o100 != 0 {
+			nw.str("executable")
+			nw.str("")
+		}
+		nw.str("contents")
+		nw.file(path, info.Size())
+	}
+	nw.str(")")
+}
+
+// like str, but streaming the file contents.
+func (nw *narWriter) file(path string, size int64) {
+	if nw.err != nil {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		nw.err = err
+		return
+	}
+	defer f.Close()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(size))
+	if _, nw.err = nw.w.Write(buf[:]); nw.err != nil {
+		return
+	}
+	if _, nw.err = io.CopyN(nw.w, f, size); nw.err != nil {
+		return
+	}
+	if pad := (8 - size%8) % 8; pad > 0 {
+		_, nw.err = nw.w.Write(make([]byte, pad))
+	}
+}
+
+const base32Chars = "0123456789abcdfghijklmnpqrsvwxyz"
+
+// Base32 encodes hash the way Nix does, eg. for `sha256 = "..."` attributes.
+func Base32(hash []byte) string {
+	n := (len(hash)*8-1)/5 + 1
+	out := make([]byte, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		b := i * 5
+		j, k := b/8, b%8
+		c := hash[j] >> k
+		if j+1 < len(hash) {
+			c |= hash[j+1] << (8 - k)
+		}
+		out = append(out, base32Chars[c&0x1f])
+	}
+	return string(out)
+}
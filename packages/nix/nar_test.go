@@ -0,0 +1,120 @@
+package nix
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBase32(t *testing.T) {
+	empty := sha256.Sum256(nil)
+	assert(t, Base32(empty[:]) == "0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73", Base32(empty[:]))
+}
+
+func TestWriteNar_regular_file(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hello")
+	assertNoErr(t, os.WriteFile(file, []byte("hi"), 0o644))
+	buff := bytes.Buffer{}
+	assertNoErr(t, WriteNar(&buff, file))
+
+	expected := bytes.Buffer{}
+	for _, s := range []string{"nix-archive-1", "(", "type", "regular", "contents", "hi", ")"} {
+		(&narWriter{w: &expected}).str(s)
+	}
+	assert(t, bytes.Equal(buff.Bytes(), expected.Bytes()), "nar of regular file")
+	assert(t, buff.Len()%8 == 0, "padded")
+}
+
+func tarball(entries ...*tar.Header) *bytes.Buffer {
+	tgz := &bytes.Buffer{}
+	gz := gzip.NewWriter(tgz)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range entries {
+		tw.WriteHeader(hdr)
+		tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+	}
+	tw.Close()
+	gz.Close()
+	return tgz
+}
+
+func TestUnpackTarball_stays_on_its_root(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	assertNoErr(t, os.WriteFile(secret, []byte("top-secret"), 0o600))
+	outside := t.TempDir()
+
+	malicious := map[string]*bytes.Buffer{
+		"dot-dot name": tarball(&tar.Header{Typeflag: tar.TypeReg, Name: "repo/../../evil", Size: 1}),
+		"hard link to host file": tarball(
+			&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0o755},
+			&tar.Header{Typeflag: tar.TypeLink, Name: "repo/secret", Linkname: "../../../../../../.." + secret},
+		),
+		"absolute hard link": tarball(
+			&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0o755},
+			&tar.Header{Typeflag: tar.TypeLink, Name: "repo/secret", Linkname: secret},
+		),
+		"write through symlinked dir": tarball(
+			&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0o755},
+			&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo/out", Linkname: outside},
+			&tar.Header{Typeflag: tar.TypeReg, Name: "repo/out/evil", Mode: 0o644, Size: 1},
+		),
+		"overwrite through symlink": tarball(
+			&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0o755},
+			&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo/secret", Linkname: secret},
+			&tar.Header{Typeflag: tar.TypeReg, Name: "repo/secret", Mode: 0o644, Size: 1},
+		),
+		"hard link through symlink": tarball(
+			&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0o755},
+			&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo/tmp", Linkname: filepath.Dir(secret)},
+			&tar.Header{Typeflag: tar.TypeLink, Name: "repo/secret", Linkname: "repo/tmp/secret"},
+		),
+	}
+	for name, tgz := range malicious {
+		_, err := UnpackTarball(tgz, t.TempDir())
+		assert(t, err != nil, name+" should fail")
+	}
+
+	content, _ := os.ReadFile(secret)
+	assert(t, string(content) == "top-secret", "host file untouched")
+	entries, _ := os.ReadDir(outside)
+	assert(t, len(entries) == 0, "nothing written outside")
+}
+
+func TestUnpackTarball_and_hash(t *testing.T) {
+	tgz := bytes.Buffer{}
+	gz := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header"})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "repo-abc/", Mode: 0o755})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc/run.sh", Mode: 0o755, Size: 4})
+	tw.Write([]byte("echo"))
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "repo-abc/link", Linkname: "run.sh"})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeLink, Name: "repo-abc/same.sh", Linkname: "repo-abc/run.sh"})
+	tw.Close()
+	gz.Close()
+
+	root, err := UnpackTarball(&tgz, t.TempDir())
+	assertNoErr(t, err)
+	assert(t, filepath.Base(root) == "repo-abc", root)
+
+	buff := bytes.Buffer{}
+	assertNoErr(t, WriteNar(&buff, root))
+	assert(t, bytes.Contains(buff.Bytes(), []byte("executable")), "executable kept")
+	assert(t, bytes.Index(buff.Bytes(), []byte("link")) < bytes.Index(buff.Bytes(), []byte("run.sh")), "sorted entries")
+}
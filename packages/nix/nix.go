@@ -1,16 +1,21 @@
 package nix
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"os/exec"
 	"slices"
+
+	"github.com/vic/ntv/packages/throttle"
 )
 
 type JsonMap = map[string]any
 
 var (
 	flakes_enabled []string
+	// When true, nix commands are run with `--offline`.
+	Offline bool
 )
 
 func init() {
@@ -20,8 +25,13 @@ func init() {
 	}
 }
 
-func Run(bin string, args ...string) (string, error) {
-	cmd := exec.Command(bin, args...)
+func Run(ctx context.Context, bin string, args ...string) (string, error) {
+	release, err := throttle.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	cmd := exec.CommandContext(ctx, bin, args...)
 	cmd.Stderr = nil // capture stdout on err
 	output, err := cmd.Output()
 	if err != nil {
@@ -30,11 +40,14 @@ func Run(bin string, args ...string) (string, error) {
 	return string(output), nil
 }
 
-func NixRun(args ...string) (string, error) {
-	return Run("nix", slices.Concat(flakes_enabled, args)...)
+func NixRun(ctx context.Context, args ...string) (string, error) {
+	if Offline {
+		args = slices.Concat([]string{"--offline"}, args)
+	}
+	return Run(ctx, "nix", slices.Concat(flakes_enabled, args)...)
 }
 
-func JsonToNix(json string) (string, error) {
+func JsonToNix(ctx context.Context, json string) (string, error) {
 	tmpFile, err := os.CreateTemp("", "json-to-nix-*.json")
 	if err != nil {
 		return "", err
@@ -51,6 +64,7 @@ func JsonToNix(json string) (string, error) {
 	}
 
 	return Run(
+		ctx,
 		"nix-instantiate",
 		"--eval",
 		"--expr",
@@ -59,8 +73,13 @@ func JsonToNix(json string) (string, error) {
 	)
 }
 
-func Nixfmt(args ...string) error {
+func NixToJSON(ctx context.Context, expr string) (string, error) {
+	return Run(ctx, "nix-instantiate", "--eval", "--strict", "--json", "--expr", expr)
+}
+
+func Nixfmt(ctx context.Context, args ...string) error {
 	_, err := NixRun(
+		ctx,
 		slices.Concat(
 			[]string{"run", "nixpkgs#nixfmt-rfc-style", "--"},
 			args,
@@ -69,7 +88,7 @@ func Nixfmt(args ...string) error {
 	return err
 }
 
-func NixfmtCode(code string) (string, error) {
+func NixfmtCode(ctx context.Context, code string) (string, error) {
 	tmpFile, err := os.CreateTemp("", "code-*.nix")
 	if err != nil {
 		return "", err
@@ -85,7 +104,7 @@ func NixfmtCode(code string) (string, error) {
 		return "", err
 	}
 
-	err = Nixfmt(tmpFile.Name())
+	err = Nixfmt(ctx, tmpFile.Name())
 	if err != nil {
 		return "", err
 	}
@@ -95,8 +114,8 @@ func NixfmtCode(code string) (string, error) {
 	return string(res), err
 }
 
-func NvJSON(flakePath string) (string, error) {
-	return NixRun("eval", "--json", (flakePath + "#lib.ntv"))
+func NvJSON(ctx context.Context, flakePath string) (string, error) {
+	return NixRun(ctx, "eval", "--json", (flakePath + "#lib.ntv"))
 }
 
 type PackageVersion struct {
@@ -104,8 +123,8 @@ type PackageVersion struct {
 	Version     string
 }
 
-func InstallablePackageVersion(installable string) (*PackageVersion, error) {
-	out, err := NixRun("eval", "--json", installable, "--apply", "p: let version = if p ? version then p.version else throw \""+installable+" is NOT an installable.\"; name = builtins.replaceStrings [(\"-\" + version)] [\"\"] (p.pname or p.name); in {  inherit name version;  }")
+func InstallablePackageVersion(ctx context.Context, installable string) (*PackageVersion, error) {
+	out, err := NixRun(ctx, "eval", "--json", installable, "--apply", "p: let version = if p ? version then p.version else throw \""+installable+" is NOT an installable.\"; name = builtins.replaceStrings [(\"-\" + version)] [\"\"] (p.pname or p.name); in {  inherit name version;  }")
 	if err != nil {
 		return nil, err
 	}
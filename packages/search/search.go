@@ -2,16 +2,17 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"golang.org/x/sync/errgroup"
 
-	"github.com/vic/ntv/packages/backends/lazamar"
-	"github.com/vic/ntv/packages/backends/nix_packages_com"
-	"github.com/vic/ntv/packages/backends/nixhub"
+	"github.com/vic/ntv/packages/backends"
 	"github.com/vic/ntv/packages/backends/nixsearch"
-	"github.com/vic/ntv/packages/nix"
+	"github.com/vic/ntv/packages/cache"
 	ss "github.com/vic/ntv/packages/search_spec"
 	"github.com/vic/ntv/packages/versions"
 	lib "github.com/vic/ntv/packages/versions"
@@ -22,66 +23,111 @@ type PackageSearchSpec ss.PackageSearchSpec
 
 type PackageSearchResult struct {
 	FromSearch  *PackageSearchSpec
+	Backend     backends.VersionsBackend
 	Versions    []*lib.Version
 	Constrained []*lib.Version
 	Selected    *lib.Version
 	Package     *nixsearch.Package
+	// The spec matched more than NixSearch.Limit packages, only the first ones were searched.
+	Truncated bool
 }
 
 type PackageSearchResults []*PackageSearchResult
 
-func (s *PackageSearchSpec) findNixpkgs() ([]nixsearch.Package, error) {
+// findNixpkgs also tells if more than NixSearch.Limit packages matched.
+// One more package than the limit is requested to know it.
+func (s *PackageSearchSpec) findNixpkgs(ctx context.Context) ([]nixsearch.Package, bool, error) {
 	var (
-		pkgs []nixsearch.Package
-		err  error
+		pkgs    []nixsearch.Package
+		err     error
+		channel = s.NixSearch.Channel
+		maxRes  = s.NixSearch.Limit + 1
+		limit   = strconv.Itoa(maxRes)
 	)
 	if strings.HasPrefix(*s.Query, "bin/") {
 		program := strings.TrimPrefix(*s.Query, "bin/")
-		pkgs, err = nixsearch.FindPackagesWithProgram(10, program)
+		pkgs, err = cache.Fetch(cache.Default, cache.Key("nixsearch", channel, "program", limit, program), func() ([]nixsearch.Package, error) {
+			return nixsearch.FindPackagesWithProgram(ctx, channel, maxRes, program)
+		})
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		if len(pkgs) < 1 {
-			return nil, fmt.Errorf("no packages found providing program `bin/%s`. try using `bin/*%s*`", program, program)
+			return nil, false, fmt.Errorf("no packages found providing program `bin/%s`. try using `bin/*%s*`", program, program)
 		}
 	} else {
-		pkgs, err = nixsearch.FindPackagesWithAttr(10, *s.Query)
+		pkgs, err = cache.Fetch(cache.Default, cache.Key("nixsearch", channel, "attr", limit, *s.Query), func() ([]nixsearch.Package, error) {
+			return nixsearch.FindPackagesWithAttr(ctx, channel, maxRes, *s.Query)
+		})
+		// offline, an exact attribute name needs no search.
+		if errors.Is(err, cache.ErrNotCached) && ss.SimpleAttrRegex.MatchString(*s.Query) {
+			pkgs, err = []nixsearch.Package{{AttrName: *s.Query}}, nil
+		}
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		if len(pkgs) < 1 {
-			return nil, fmt.Errorf("no packages found for attribute-path `%s`. try using `*%s*`", *s.Query, *s.Query)
+			return nil, false, fmt.Errorf("no packages found for attribute-path `%s`. try using `*%s*`", *s.Query, *s.Query)
+		}
+	}
+	if len(pkgs) > s.NixSearch.Limit {
+		return pkgs[:s.NixSearch.Limit], true, nil
+	}
+	return pkgs, false, nil
+}
+
+// a local nixpkgs clone can be searched for an exact attribute without nixos-search.
+func (s *PackageSearchSpec) isLocalAttr() bool {
+	for _, b := range backends.Each(s.VersionsBackend) {
+		if _, isGit := b.(*backends.Git); !isGit {
+			return false
 		}
 	}
-	return pkgs, nil
+	return ss.SimpleAttrRegex.MatchString(*s.Query)
 }
 
 func (s *PackageSearchSpec) isNotNixpkgs() bool {
-	return s.VersionsBackend != nil && s.VersionsBackend.FlakeInstallable != nil
+	_, isFlake := s.VersionsBackend.(*backends.Flake)
+	return isFlake
 }
 
-func (s *PackageSearchSpec) Search() ([]*PackageSearchResult, error) {
+func (s *PackageSearchSpec) Search(ctx context.Context) ([]*PackageSearchResult, error) {
+	res, err := s.search(ctx)
+	if errors.Is(err, cache.ErrNotCached) {
+		return nil, fmt.Errorf("cannot resolve `%s` offline: %w", *s.Spec, err)
+	}
+	return res, err
+}
+
+func (s *PackageSearchSpec) search(ctx context.Context) ([]*PackageSearchResult, error) {
 	if s.isNotNixpkgs() {
-		res, err := s.searchVersions(nil)
+		res, err := s.searchVersions(ctx, nil)
 		if err != nil {
 			return nil, err
 		}
 		return []*PackageSearchResult{res}, nil
 	}
 
-	pkgs, err := s.findNixpkgs()
-	if err != nil {
-		return nil, err
+	var (
+		pkgs      = []nixsearch.Package{{AttrName: *s.Query}}
+		truncated bool
+	)
+	if !s.isLocalAttr() {
+		var err error
+		if pkgs, truncated, err = s.findNixpkgs(ctx); err != nil {
+			return nil, err
+		}
 	}
-	group, _ := errgroup.WithContext(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
 	acc := make([]*PackageSearchResult, len(pkgs))
 	for i, pkg := range pkgs {
 		i, pkg := i, pkg
 		group.Go(func() error {
-			res, err := s.searchVersions(&pkg)
+			res, err := s.searchVersions(ctx, &pkg)
 			if err != nil {
 				return err
 			}
+			res.Truncated = truncated
 			acc[i] = res
 			return nil
 		})
@@ -92,76 +138,46 @@ func (s *PackageSearchSpec) Search() ([]*PackageSearchResult, error) {
 	return acc, nil
 }
 
-func (s *PackageSearchSpec) searchVersions(pkg *nixsearch.Package) (*PackageSearchResult, error) {
+// Each backend on a fallback chain is tried until one of them
+// has a version satisfying the spec constraint.
+func (s *PackageSearchSpec) searchVersions(ctx context.Context, pkg *nixsearch.Package) (*PackageSearchResult, error) {
+	var result *PackageSearchResult
+	chain := backends.Chain(backends.Each(s.VersionsBackend))
+	err := chain.Fallback(ctx, func(b backends.VersionsBackend) (bool, error) {
+		res, err := s.searchBackendVersions(ctx, b, pkg)
+		if err != nil {
+			return false, err
+		}
+		result = res
+		return result.Selected != nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *PackageSearchSpec) searchBackendVersions(ctx context.Context, b backends.VersionsBackend, pkg *nixsearch.Package) (*PackageSearchResult, error) {
 	var (
 		versions []*lib.Version
 		result   *PackageSearchResult
 		err      error
 	)
 
-	if s.VersionsBackend.CurrentNixpkgs != nil {
-		installable := "nixpkgs#" + pkg.AttrName
-		pv, err := nix.InstallablePackageVersion(installable)
-		if err != nil {
-			return nil, err
-		}
-		one := lib.Version{
-			Name:      pv.PackageName,
-			Version:   pv.Version,
-			Attribute: pkg.AttrName,
-			Flake:     "nixpkgs",
-			Revision:  "",
-		}
-		versions = []*lib.Version{&one}
-	}
-
-	if s.VersionsBackend.FlakeInstallable != nil {
-		installable := string(*s.VersionsBackend.FlakeInstallable)
-		pv, err := nix.InstallablePackageVersion(installable)
-		if err != nil {
-			return nil, err
-		}
-		var (
-			attribute = "default"
-			flake     = installable
-		)
-		if strings.Contains(installable, "#") {
-			idx := strings.LastIndex(installable, "#")
-			flake = installable[:idx]
-			attribute = installable[idx+1:]
-		}
-		one := lib.Version{
-			Name:      pv.PackageName,
-			Version:   pv.Version,
-			Attribute: attribute,
-			Flake:     flake,
-			Revision:  "",
-		}
-		versions = []*lib.Version{&one}
-	}
-
-	if s.VersionsBackend.NixPackagesCom != nil {
-		if versions, err = nix_packages_com.Search(pkg.AttrName); err != nil {
-			return nil, err
-		}
-	}
-
-	if s.VersionsBackend.NixHub != nil {
-		if versions, err = nixhub.Search(pkg.AttrName); err != nil {
-			return nil, err
-		}
+	var attr string
+	if pkg != nil {
+		attr = pkg.AttrName
 	}
 
-	if s.VersionsBackend.LazamarChannel != nil {
-		if versions, err = lazamar.Search(pkg.AttrName, string(*s.VersionsBackend.LazamarChannel)); err != nil {
-			return nil, err
-		}
+	if versions, err = searchBackend(ctx, b, attr); err != nil {
+		return nil, err
 	}
 
 	lib.SortByVersion(versions)
 
 	result = &PackageSearchResult{
 		FromSearch:  s,
+		Backend:     b,
 		Versions:    versions,
 		Constrained: []*lib.Version{},
 		Package:     pkg,
@@ -186,13 +202,28 @@ func (s *PackageSearchSpec) searchVersions(pkg *nixsearch.Package) (*PackageSear
 	return result, nil
 }
 
-func (ss PackageSearchSpecs) Search() (PackageSearchResults, error) {
-	group, _ := errgroup.WithContext(context.Background())
+// Remote backend responses are cached under the backend name, eg. `lazamar/nixos-24.05/emacs`
+func searchBackend(ctx context.Context, b backends.VersionsBackend, attr string) ([]*lib.Version, error) {
+	if m, isMerged := b.(backends.Merged); isMerged {
+		return m.SearchWith(ctx, attr, searchBackend)
+	}
+	if _, isRemote := b.(backends.Remote); !isRemote {
+		return b.Search(ctx, attr)
+	}
+	key := cache.Key(append(strings.Split(b.Name(), ":"), attr)...)
+	return cache.Fetch(cache.Default, key, func() ([]*lib.Version, error) {
+		return b.Search(ctx, attr)
+	})
+}
+
+// Search all specs concurrently. The first error cancels the searches still in flight.
+func (ss PackageSearchSpecs) Search(ctx context.Context) (PackageSearchResults, error) {
+	group, ctx := errgroup.WithContext(ctx)
 	results := make([][]*PackageSearchResult, len(ss))
 	for i, s := range ss {
 		i, s := i, (*PackageSearchSpec)(s)
 		group.Go(func() error {
-			res, err := s.Search()
+			res, err := s.Search(ctx)
 			if err != nil {
 				return err
 			}
@@ -210,6 +241,17 @@ func (ss PackageSearchSpecs) Search() (PackageSearchResults, error) {
 	return res, nil
 }
 
+// TruncatedSpecs are the specs that matched more packages than were searched.
+func (r PackageSearchResults) TruncatedSpecs() []*PackageSearchSpec {
+	specs := []*PackageSearchSpec{}
+	for _, result := range r {
+		if result.Truncated && !slices.Contains(specs, result.FromSearch) {
+			specs = append(specs, result.FromSearch)
+		}
+	}
+	return specs
+}
+
 func (r PackageSearchResults) EnsureOneSelected() error {
 	for _, result := range r {
 		if result.Selected == nil {
@@ -234,6 +276,92 @@ func (r PackageSearchResults) EnsureUniquePackageNames() error {
 	return nil
 }
 
+// PreferCommonRevisions sets the Revision of merged versions to the one
+// appearing most often among the selected versions of the result set.
+func (r PackageSearchResults) PreferCommonRevisions() {
+	count := map[string]int{}
+	for _, result := range r {
+		if result.Selected == nil {
+			continue
+		}
+		for _, rev := range result.Selected.AllRevisions() {
+			count[rev]++
+		}
+	}
+	for _, result := range r {
+		for _, v := range result.Versions {
+			for _, rev := range v.Revisions {
+				if count[rev] > count[v.Revision] {
+					v.Revision = rev
+				}
+			}
+		}
+	}
+}
+
+// MinimizeRevisions selects, for every result, a version from its Constrained set
+// so that the whole result set uses as few distinct nixpkgs revisions as possible.
+// Each nixpkgs checkout is about 40M.
+//
+// This is a set-cover problem, solved greedily: the revision covering most
+// results is taken first, preferring revisions of newer versions on ties.
+// Then each result selects its newest version on a taken revision.
+func (r PackageSearchResults) MinimizeRevisions() {
+	pending := PackageSearchResults{}
+	for _, result := range r {
+		if result.Selected != nil && len(result.Selected.AllRevisions()) > 0 {
+			pending = append(pending, result)
+		}
+	}
+
+	taken := []string{}
+	for len(pending) > 0 {
+		covers := map[string]int{}
+		newest := map[string]int{}
+		for _, result := range pending {
+			seen := map[string]bool{}
+			for i, v := range result.Constrained {
+				for _, rev := range v.AllRevisions() {
+					if !seen[rev] {
+						seen[rev] = true
+						covers[rev]++
+					}
+					if i == len(result.Constrained)-1 {
+						newest[rev]++
+					}
+				}
+			}
+		}
+		var best string
+		for rev := range covers {
+			if best == "" || covers[rev] > covers[best] ||
+				(covers[rev] == covers[best] && newest[rev] > newest[best]) ||
+				(covers[rev] == covers[best] && newest[rev] == newest[best] && rev < best) {
+				best = rev
+			}
+		}
+		taken = append(taken, best)
+		pending = slices.DeleteFunc(pending, func(result *PackageSearchResult) bool {
+			return result.selectNewestOn(taken)
+		})
+	}
+}
+
+// selects the newest constrained version available on one of revs.
+func (r *PackageSearchResult) selectNewestOn(revs []string) bool {
+	for i := len(r.Constrained) - 1; i >= 0; i-- {
+		v := r.Constrained[i]
+		for _, rev := range revs {
+			if slices.Contains(v.AllRevisions(), rev) {
+				v.Revision = rev
+				r.Selected = v
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (r PackageSearchResults) Size() int {
 	var size = 0
 	for _, result := range r {
@@ -242,14 +370,17 @@ func (r PackageSearchResults) Size() int {
 	return size
 }
 
+func ShortRevision(rev string) string {
+	if len(rev) == 40 {
+		rev = rev[:7]
+	}
+	return rev
+}
+
 func (r PackageSearchResult) FlakeUrl(v *versions.Version) string {
 	var url = v.Flake
 	if len(v.Revision) > 0 {
-		rev := v.Revision
-		if len(rev) == 40 {
-			rev = rev[:7]
-		}
-		url = fmt.Sprintf("%s/%s", v.Flake, rev)
+		url = fmt.Sprintf("%s/%s", v.Flake, ShortRevision(v.Revision))
 	}
 	return url
 }
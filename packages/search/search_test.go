@@ -0,0 +1,71 @@
+package search
+
+import (
+	"testing"
+
+	lib "github.com/vic/ntv/packages/versions"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func result(versions ...*lib.Version) *PackageSearchResult {
+	return &PackageSearchResult{
+		Versions:    versions,
+		Constrained: versions,
+		Selected:    versions[len(versions)-1],
+	}
+}
+
+func ver(version, revision string) *lib.Version {
+	return &lib.Version{Version: version, Revision: revision}
+}
+
+func TestMinimizeRevisions_single_common_revision(t *testing.T) {
+	a := result(ver("1.0", "r1"), ver("1.1", "r2"), ver("1.2", "r3"))
+	b := result(ver("2.0", "r2"), ver("2.1", "r4"))
+	c := result(ver("3.0", "r2"), ver("3.1", "r3"))
+	PackageSearchResults{a, b, c}.MinimizeRevisions()
+	assert(t, a.Selected.Version == "1.1", "a on r2")
+	assert(t, b.Selected.Version == "2.0", "b on r2")
+	assert(t, c.Selected.Version == "3.0", "c on r2")
+}
+
+func TestMinimizeRevisions_prefers_newest_on_ties(t *testing.T) {
+	a := result(ver("1.0", "r1"), ver("1.1", "r2"))
+	b := result(ver("2.0", "r1"), ver("2.1", "r2"))
+	PackageSearchResults{a, b}.MinimizeRevisions()
+	assert(t, a.Selected.Version == "1.1", "a newest")
+	assert(t, b.Selected.Version == "2.1", "b newest")
+}
+
+func TestMinimizeRevisions_uses_merged_revisions(t *testing.T) {
+	merged := ver("1.1", "r9")
+	merged.Revisions = []string{"r9", "r5"}
+	a := result(ver("1.0", "r1"), merged)
+	b := result(ver("2.0", "r5"))
+	PackageSearchResults{a, b}.MinimizeRevisions()
+	assert(t, a.Selected == merged && merged.Revision == "r5", "a on r5")
+	assert(t, b.Selected.Version == "2.0", "b on r5")
+}
+
+func TestMinimizeRevisions_skips_results_without_revision(t *testing.T) {
+	system := result(ver("1.0", ""))
+	b := result(ver("2.0", "r1"), ver("2.1", "r2"))
+	PackageSearchResults{system, b}.MinimizeRevisions()
+	assert(t, system.Selected.Version == "1.0", "system untouched")
+	assert(t, b.Selected.Version == "2.1", "b newest")
+}
+
+func TestTruncatedSpecs_once_per_spec(t *testing.T) {
+	python, hello := &PackageSearchSpec{}, &PackageSearchSpec{}
+	a, b, c := result(ver("3.12", "r1")), result(ver("3.13", "r1")), result(ver("2.12", "r1"))
+	a.FromSearch, b.FromSearch, c.FromSearch = python, python, hello
+	a.Truncated, b.Truncated = true, true
+
+	specs := PackageSearchResults{a, b, c}.TruncatedSpecs()
+	assert(t, len(specs) == 1 && specs[0] == python, "only python was truncated")
+}
@@ -8,54 +8,51 @@ import (
 	"strings"
 
 	"golang.org/x/sync/errgroup"
+
+	"github.com/vic/ntv/packages/backends"
 )
 
 type PackageSearchSpecs []*PackageSearchSpec
 
-type Unit struct{}
-type LazamarChannel string
-type FlakeInstallable string
-
-// a kind of union type. only one of these should be set.
-type VersionsBackend struct {
-	CurrentNixpkgs   *Unit
-	NixHub           *Unit
-	LazamarChannel   *LazamarChannel
-	FlakeInstallable *FlakeInstallable
-	NixPackagesCom   *Unit
-}
+type VersionsBackend = backends.VersionsBackend
 
 type PackageSearchSpec struct {
 	Spec              *string
 	Query             *string
 	OutputSelectors   []string
 	VersionConstraint *string
-	VersionsBackend   *VersionsBackend
+	VersionsBackend   VersionsBackend
+	NixSearch         NixSearch
 }
 
-func (b VersionsBackend) String() string {
-	if b.CurrentNixpkgs != nil {
-		return "system"
-	}
-	if b.NixHub != nil {
-		return "nixhub"
-	}
-	if b.LazamarChannel != nil {
-		return "lazamar:" + string(*b.LazamarChannel)
-	}
-	if b.NixPackagesCom != nil {
-		return "history"
-	}
-	return "flake"
+// NixSearch options used to find packages on https://search.nixos.org
+type NixSearch struct {
+	// eg. `unstable` or `24.11`
+	Channel string
+	// Maximum number of packages matching a query.
+	Limit int
 }
 
+var DefaultNixSearch = NixSearch{Channel: "unstable", Limit: 10}
+
+// ParseSearchSpecs reads the version constraint from a file when it names one, eg. `go@.go-version`
 func ParseSearchSpecs(args []string, defaultBackend VersionsBackend) (PackageSearchSpecs, error) {
+	return parseSearchSpecs(args, defaultBackend, true)
+}
+
+// ParseRemoteSearchSpecs is like ParseSearchSpecs for specs sent by remote clients.
+// Version constraints are never read from local files.
+func ParseRemoteSearchSpecs(args []string, defaultBackend VersionsBackend) (PackageSearchSpecs, error) {
+	return parseSearchSpecs(args, defaultBackend, false)
+}
+
+func parseSearchSpecs(args []string, defaultBackend VersionsBackend, readFiles bool) (PackageSearchSpecs, error) {
 	group, _ := errgroup.WithContext(context.Background())
 	specs := make(PackageSearchSpecs, len(args))
 	for i, pkg := range args {
 		i, pkg := i, pkg
 		group.Go(func() error {
-			s, err := newPackageSearchSpec(pkg, defaultBackend)
+			s, err := newPackageSearchSpec(pkg, defaultBackend, readFiles)
 			if err != nil {
 				return err
 			}
@@ -69,19 +66,24 @@ func ParseSearchSpecs(args []string, defaultBackend VersionsBackend) (PackageSea
 	return specs, nil
 }
 
+// WithNixSearch sets the search.nixos.org options of every spec.
+func (ss PackageSearchSpecs) WithNixSearch(n NixSearch) PackageSearchSpecs {
+	for _, s := range ss {
+		s.NixSearch = n
+	}
+	return ss
+}
+
 func (s *PackageSearchSpec) HasBackend() bool {
-	return !(s.VersionsBackend == nil || (s.VersionsBackend.CurrentNixpkgs == nil &&
-		s.VersionsBackend.NixHub == nil &&
-		s.VersionsBackend.LazamarChannel == nil &&
-		s.VersionsBackend.FlakeInstallable == nil &&
-		s.VersionsBackend.NixPackagesCom == nil))
+	return s.VersionsBackend != nil
 }
 
-func newPackageSearchSpec(spec string, defaultBackend VersionsBackend) (*PackageSearchSpec, error) {
+func newPackageSearchSpec(spec string, defaultBackend VersionsBackend, readFiles bool) (*PackageSearchSpec, error) {
 	original_spec := strings.Clone(spec)
 	s := &PackageSearchSpec{
-		Spec:  &original_spec,
-		Query: &spec,
+		Spec:      &original_spec,
+		Query:     &spec,
+		NixSearch: DefaultNixSearch,
 	}
 
 	// has version constraint
@@ -92,7 +94,7 @@ func newPackageSearchSpec(spec string, defaultBackend VersionsBackend) (*Package
 		s.Query = &q
 		s.VersionConstraint = &v
 
-		if fileExists(*s.VersionConstraint) {
+		if readFiles && fileExists(*s.VersionConstraint) {
 			content, err := readFile(*s.VersionConstraint)
 			if err != nil {
 				return nil, err
@@ -110,46 +112,18 @@ func newPackageSearchSpec(spec string, defaultBackend VersionsBackend) (*Package
 		s.OutputSelectors = strings.Split(v, ",")
 	}
 
-	if strings.HasPrefix(*s.Query, "system:") {
-		str := strings.TrimPrefix(*s.Query, "system:")
-		s.Query = &str
-		s.VersionsBackend = &VersionsBackend{CurrentNixpkgs: &Unit{}}
-	}
-
-	if strings.HasPrefix(*s.Query, "history:") {
-		str := strings.TrimPrefix(*s.Query, "history:")
-		s.Query = &str
-		s.VersionsBackend = &VersionsBackend{NixPackagesCom: &Unit{}}
-	}
-
-	if strings.HasPrefix(*s.Query, "nixhub:") {
-		str := strings.TrimPrefix(*s.Query, "nixhub:")
-		s.Query = &str
-		s.VersionsBackend = &VersionsBackend{NixHub: &Unit{}}
-	}
-
-	// lazamar:channel:package or lazamar:package
-	if strings.HasPrefix(*s.Query, "lazamar:") {
-		str := strings.TrimPrefix(*s.Query, "lazamar:")
-		s.Query = &str
-		var channel = "nixpkgs-unstable"
-		if defaultBackend.LazamarChannel != nil {
-			channel = string(*defaultBackend.LazamarChannel)
-		}
-		if strings.Contains(*s.Query, ":") {
-			parts := strings.SplitN(*s.Query, ":", 2)
-			channel = parts[0]
-			s.Query = &parts[1]
-		}
-		s.VersionsBackend = &VersionsBackend{LazamarChannel: (*LazamarChannel)(&channel)}
+	backend, query, err := backends.FromQuery(*s.Query, defaultBackend)
+	if err != nil {
+		return nil, err
 	}
+	s.Query = &query
+	s.VersionsBackend = backend
 
 	if !s.HasBackend() {
 		if strings.HasPrefix(*s.Query, "bin/") || !strings.ContainsAny(*s.Query, "/:#") {
-			s.VersionsBackend = &defaultBackend
+			s.VersionsBackend = defaultBackend
 		} else {
-			installable := *s.Query
-			s.VersionsBackend = &VersionsBackend{FlakeInstallable: (*FlakeInstallable)(&installable)}
+			s.VersionsBackend = &backends.Flake{Installable: *s.Query}
 		}
 	}
 
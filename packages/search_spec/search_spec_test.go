@@ -0,0 +1,95 @@
+package search_spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vic/ntv/packages/backends"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewPackageSearchSpec_default_backend(t *testing.T) {
+	s, err := newPackageSearchSpec("emacs@29", &backends.NixHub{}, true)
+	assertNoErr(t, err)
+	assert(t, *s.Query == "emacs", "query")
+	assert(t, *s.VersionConstraint == "29", "constraint")
+	assert(t, s.VersionsBackend.Name() == "nixhub", "default backend")
+}
+
+func TestNewPackageSearchSpec_prefix_backend(t *testing.T) {
+	s, err := newPackageSearchSpec("history:emacs", &backends.NixHub{}, true)
+	assertNoErr(t, err)
+	assert(t, *s.Query == "emacs", "query")
+	assert(t, s.VersionsBackend.Name() == "history", "prefixed backend")
+}
+
+func TestNewPackageSearchSpec_lazamar_channel(t *testing.T) {
+	s, err := newPackageSearchSpec("lazamar:nixos-24.05:emacs", &backends.NixHub{}, true)
+	assertNoErr(t, err)
+	assert(t, *s.Query == "emacs", "query")
+	assert(t, s.VersionsBackend.Name() == "lazamar:nixos-24.05", "lazamar channel")
+}
+
+func TestNewPackageSearchSpec_lazamar_default_channel(t *testing.T) {
+	s, err := newPackageSearchSpec("lazamar:emacs", &backends.Lazamar{Channel: "nixos-23.11"}, true)
+	assertNoErr(t, err)
+	assert(t, s.VersionsBackend.Name() == "lazamar:nixos-23.11", "lazamar channel from default")
+}
+
+func TestNewPackageSearchSpec_flake_installable(t *testing.T) {
+	s, err := newPackageSearchSpec("github:foo/bar#baz^out", &backends.NixHub{}, true)
+	assertNoErr(t, err)
+	f, isFlake := s.VersionsBackend.(*backends.Flake)
+	assert(t, isFlake, "flake backend")
+	assert(t, f.Installable == "github:foo/bar#baz", "installable")
+	assert(t, s.OutputSelectors[0] == "out", "output selectors")
+}
+
+func TestNewPackageSearchSpec_fallback_chain(t *testing.T) {
+	s, err := newPackageSearchSpec("nixhub|lazamar:ripgrep@14", &backends.History{}, true)
+	assertNoErr(t, err)
+	assert(t, *s.Query == "ripgrep", "query")
+	assert(t, *s.VersionConstraint == "14", "constraint")
+	assert(t, s.VersionsBackend.Name() == "nixhub,lazamar:nixpkgs-unstable", "chain")
+}
+
+func TestNewPackageSearchSpec_fallback_chain_without_last_prefix(t *testing.T) {
+	s, err := newPackageSearchSpec("history|lazamar:nixos-24.05|ripgrep", &backends.NixHub{}, true)
+	assertNoErr(t, err)
+	assert(t, *s.Query == "ripgrep", "query")
+	assert(t, len(backends.Each(s.VersionsBackend)) == 2, "chain of two")
+	assert(t, s.VersionsBackend.Name() == "history,lazamar:nixos-24.05", "chain")
+}
+
+func TestWithNixSearch(t *testing.T) {
+	specs, err := ParseSearchSpecs([]string{"hello", "*python*"}, &backends.NixHub{})
+	assertNoErr(t, err)
+	assert(t, specs[0].NixSearch == DefaultNixSearch, "default nix search")
+	specs.WithNixSearch(NixSearch{Channel: "24.11", Limit: 50})
+	assert(t, specs[1].NixSearch.Channel == "24.11", "channel")
+	assert(t, specs[1].NixSearch.Limit == 50, "limit")
+}
+
+func TestParseRemoteSearchSpecs_never_reads_files(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "go-version")
+	assertNoErr(t, os.WriteFile(file, []byte("1.24\n"), 0o644))
+
+	local, err := ParseSearchSpecs([]string{"go@" + file}, &backends.NixHub{})
+	assertNoErr(t, err)
+	assert(t, *local[0].VersionConstraint == "1.24", "constraint read from file")
+
+	remote, err := ParseRemoteSearchSpecs([]string{"go@" + file}, &backends.NixHub{})
+	assertNoErr(t, err)
+	assert(t, *remote[0].VersionConstraint == file, "constraint kept as given")
+}
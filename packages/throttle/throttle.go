@@ -0,0 +1,209 @@
+package throttle
+
+// Limits how hard ntv hits remote services and the local nix.
+//
+// At most Jobs requests or nix subprocesses run at once, each host
+// gets its own token-bucket, and requests answered with 429 or 5xx
+// are retried with exponential backoff.
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJobs       = 8
+	DefaultRate       = 4.0 // requests per second on each host.
+	DefaultMaxRetries = 4
+)
+
+var (
+	jobs       = make(chan struct{}, DefaultJobs)
+	Rate       = DefaultRate
+	MaxRetries = DefaultMaxRetries
+	// Delay before the first retry, doubled on each subsequent one.
+	Backoff = 500 * time.Millisecond
+
+	limiters   = map[string]*limiter{}
+	limitersMu sync.Mutex
+)
+
+// SetJobs changes the global concurrency limit. Must be called before any search starts.
+func SetJobs(n int) {
+	if n < 1 {
+		n = 1
+	}
+	jobs = make(chan struct{}, n)
+}
+
+// Acquire a job slot, the returned function releases it.
+func Acquire(ctx context.Context) (func(), error) {
+	slots := jobs
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type limiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func hostLimiter(host string) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[host]
+	if !ok {
+		l = &limiter{tokens: burst(), last: time.Now()}
+		limiters[host] = l
+	}
+	return l
+}
+
+func burst() float64 {
+	if Rate < 1 {
+		return 1
+	}
+	return Rate
+}
+
+// wait until the host bucket has a token.
+func (l *limiter) wait(ctx context.Context) error {
+	if Rate <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(burst(), l.tokens+now.Sub(l.last).Seconds()*Rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / Rate * float64(time.Second))
+		l.mu.Unlock()
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport applies the job limit, host rate limits and retries to base.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+// Client used by every remote backend.
+var Client = &http.Client{Transport: Transport(http.DefaultTransport)}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		res, err := t.roundTrip(req)
+		if err != nil || !retryable(res.StatusCode) || attempt >= MaxRetries {
+			return res, err
+		}
+		delay := retryAfter(res)
+		if delay == 0 {
+			delay = Backoff<<attempt + rand.N(Backoff)
+		}
+		res.Body.Close()
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *transport) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := hostLimiter(req.URL.Host).wait(ctx); err != nil {
+		return nil, err
+	}
+	release, err := Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// the job slot is held until the caller is done reading the response.
+	res.Body = &releaseBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// releaseBody frees its job slot once closed or read to the end.
+// Some clients, like nix-search-cli, read the whole body but never close it.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func retryAfter(res *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
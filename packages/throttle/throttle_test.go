@@ -0,0 +1,124 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+func assertNoErr(t *testing.T, err error) {
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTransport_retries_on_429_and_5xx(t *testing.T) {
+	Backoff = time.Millisecond
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	res, err := Client.Get(srv.URL)
+	assertNoErr(t, err)
+	defer res.Body.Close()
+	assert(t, res.StatusCode == http.StatusOK, "should succeed after retries")
+	assert(t, calls.Load() == 3, "should have retried twice")
+}
+
+func TestTransport_gives_up_after_max_retries(t *testing.T) {
+	Backoff = time.Millisecond
+	MaxRetries = 2
+	defer func() { MaxRetries = DefaultMaxRetries }()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	res, err := Client.Get(srv.URL)
+	assertNoErr(t, err)
+	defer res.Body.Close()
+	assert(t, res.StatusCode == http.StatusServiceUnavailable, "should return last response")
+	assert(t, calls.Load() == 3, "should try once plus MaxRetries")
+}
+
+func TestAcquire_limits_jobs(t *testing.T) {
+	SetJobs(1)
+	defer SetJobs(DefaultJobs)
+	release, err := Acquire(context.Background())
+	assertNoErr(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(ctx)
+	assert(t, err == context.DeadlineExceeded, "second job should wait for the first")
+	release()
+	release, err = Acquire(context.Background())
+	assertNoErr(t, err)
+	release()
+}
+
+func TestTransport_holds_job_until_body_closed(t *testing.T) {
+	SetJobs(1)
+	defer SetJobs(DefaultJobs)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	res, err := Client.Get(srv.URL)
+	assertNoErr(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(ctx)
+	assert(t, err == context.DeadlineExceeded, "job should be held while reading the body")
+
+	res.Body.Close()
+	res.Body.Close()
+	release, err := Acquire(context.Background())
+	assertNoErr(t, err)
+	release()
+}
+
+func TestTransport_releases_job_when_body_read_but_not_closed(t *testing.T) {
+	SetJobs(2)
+	defer SetJobs(DefaultJobs)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		res, err := Client.Do(req)
+		assertNoErr(t, err)
+		if err == nil {
+			body, err := io.ReadAll(res.Body)
+			assertNoErr(t, err)
+			assert(t, string(body) == "ok", string(body))
+		}
+		cancel()
+	}
+	release, err := Acquire(context.Background())
+	assertNoErr(t, err)
+	release()
+}
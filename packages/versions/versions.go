@@ -3,6 +3,7 @@ package versions
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -10,16 +11,14 @@ import (
 )
 
 // TODO: Rename to Installable
-// TODO: Produce as few different revisions as possible.
-//
-//	Each nixpkgs checkout is about 40M
-//	--optimize=true
 type Version struct {
 	Name      string `json:"name"`
 	Attribute string `json:"attr_path"`
 	Version   string `json:"version"`
 	Flake     string `json:"flake"`
 	Revision  string `json:"revision"`
+	// Every nixpkgs revision known to have this version. Set by Merge.
+	Revisions []string `json:"revisions,omitempty"`
 }
 
 type ByVersion []*Version
@@ -50,6 +49,44 @@ func SortByVersion(versions []*Version) {
 	sort.Sort(ByVersion(versions))
 }
 
+// Merge deduplicates versions from many lists by their version string.
+// Each merged version keeps all the revisions known for it, in the order found.
+func Merge(lists ...[]*Version) []*Version {
+	var (
+		res       []*Version
+		byVersion = map[string]*Version{}
+	)
+	for _, list := range lists {
+		for _, v := range list {
+			merged, ok := byVersion[v.Version]
+			if !ok {
+				one := *v
+				one.Revisions = nil
+				merged = &one
+				byVersion[v.Version] = merged
+				res = append(res, merged)
+			}
+			for _, rev := range v.AllRevisions() {
+				if !slices.Contains(merged.Revisions, rev) {
+					merged.Revisions = append(merged.Revisions, rev)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// AllRevisions known for v, at least its Revision.
+func (v *Version) AllRevisions() []string {
+	if len(v.Revisions) > 0 {
+		return v.Revisions
+	}
+	if v.Revision == "" {
+		return []string{}
+	}
+	return []string{v.Revision}
+}
+
 func ConstraintBy(versions []*Version, constraint string) ([]*Version, error) {
 	constraint = strings.Replace(constraint, "latest", "", 1)
 	if strings.TrimSpace(constraint) == "" {
@@ -0,0 +1,30 @@
+package versions
+
+import (
+	"slices"
+	"testing"
+)
+
+func assert(t *testing.T, condition bool, msg string) {
+	if !condition {
+		t.Errorf("assertion failed: %s", msg)
+	}
+}
+
+func TestMerge_dedupes_by_version_keeping_revisions(t *testing.T) {
+	nixhub := []*Version{
+		{Name: "ripgrep", Version: "14.1.0", Revision: "aaa"},
+		{Name: "ripgrep", Version: "14.0.0", Revision: "bbb"},
+	}
+	lazamar := []*Version{
+		{Name: "ripgrep", Version: "14.1.0", Revision: "ccc"},
+		{Name: "ripgrep", Version: "13.0.0", Revision: "ddd"},
+		{Name: "ripgrep", Version: "14.1.0", Revision: "aaa"},
+	}
+	merged := Merge(nixhub, lazamar)
+	assert(t, len(merged) == 3, "three distinct versions")
+	assert(t, merged[0].Version == "14.1.0", "keeps order")
+	assert(t, merged[0].Revision == "aaa", "first revision found")
+	assert(t, slices.Equal(merged[0].Revisions, []string{"aaa", "ccc"}), "all known revisions")
+	assert(t, nixhub[0].Revisions == nil, "does not modify inputs")
+}